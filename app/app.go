@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -475,15 +478,20 @@ func (a *App) ReviewAppExists() (bool, error) {
 }
 
 func (a *App) ddbItem(key string) (*map[string]dynamodbtypes.AttributeValue, error) {
+	return ddbItem(a.Session, a.ddbPrimaryID(key), key)
+}
+
+// ddbPrimaryID is the primary ID of the app's DynamoDB item with the secondary ID key
+func (a *App) ddbPrimaryID(key string) string {
 	if !a.IsReviewApp() {
-		return ddbItem(a.Session, "APP#"+a.Name, key)
+		return "APP#" + a.Name
 	}
 	// TODO: move DEPLOYSTATUS to standard review app location
 	if strings.HasPrefix(key, "CONFIG") || key == "settings" || strings.HasPrefix(key, "DEPLOYSTATUS") {
-		return ddbItem(a.Session, "APP#"+a.Name, key)
+		return "APP#" + a.Name
 	}
 	// review apps are at APP#{appname}:{pr}
-	return ddbItem(a.Session, fmt.Sprintf("APP#%s:%s", a.Name, *a.ReviewApp), key)
+	return fmt.Sprintf("APP#%s:%s", a.Name, *a.ReviewApp)
 }
 
 // LoadECSConfig will set the app.ECSConfig value from DDB
@@ -777,7 +785,7 @@ func (a *App) ConnectToEcsSession(ecsSession *ecstypes.Session) error {
 }
 
 // StartBuild starts a new CodeBuild run
-func (a *App) StartBuild(createReviewApp bool, ref string) (*codebuildetypes.Build, error) {
+func (a *App) StartBuild(createReviewApp bool, options *BuildOptions) (*codebuildetypes.Build, error) {
 	codebuildSvc := codebuild.NewFromConfig(a.Session)
 
 	err := a.LoadSettings()
@@ -785,21 +793,31 @@ func (a *App) StartBuild(createReviewApp bool, ref string) (*codebuildetypes.Bui
 		return nil, err
 	}
 
+	if options.SkipTests {
+		project, err := a.codebuildProject()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ProjectSupportsSkipTests(project) {
+			return nil, fmt.Errorf("the build for %s doesn't support skipping tests -- its buildspec doesn't read %s", a.Name, SkipTestsVariable)
+		}
+	}
+
 	buildInput := codebuild.StartBuildInput{
 		ProjectName: &a.Settings.CodebuildProject.Name,
 	}
-	sourceVersion := DetermineBuildSourceVersion(a.IsReviewApp(), a.ReviewApp, ref)
-	if sourceVersion != nil {
+	if options.SourceLocation != "" {
+		buildInput.SourceTypeOverride = codebuildetypes.SourceTypeS3
+		buildInput.SourceLocationOverride = &options.SourceLocation
+	} else if sourceVersion := DetermineBuildSourceVersion(a.IsReviewApp(), a.ReviewApp, options.Ref); sourceVersion != nil {
 		buildInput.SourceVersion = sourceVersion
 	}
-	if a.IsReviewApp() && createReviewApp {
-		buildInput.EnvironmentVariablesOverride = []codebuildetypes.EnvironmentVariable{
-			{
-				Name:  aws.String("REVIEW_APP_STATUS"),
-				Value: aws.String("created"),
-				Type:  codebuildetypes.EnvironmentVariableTypePlaintext,
-			},
-		}
+	if overrides := BuildEnvironmentOverrides(options, a.IsReviewApp() && createReviewApp); len(overrides) > 0 {
+		buildInput.EnvironmentVariablesOverride = overrides
+	}
+	if options.NoCache {
+		buildInput.CacheOverride = &codebuildetypes.ProjectCache{Type: codebuildetypes.CacheTypeNoCache}
 	}
 
 	build, err := codebuildSvc.StartBuild(context.Background(), &buildInput)
@@ -888,6 +906,63 @@ func (a *App) GetBuildStatus(buildNumber int) (*BuildStatus, error) {
 	return &build, nil
 }
 
+// buildStopTimeout is how long StopBuild waits for CodeBuild to stop the build
+const buildStopTimeout = 5 * time.Minute
+
+// StopBuild stops the build in CodeBuild and waits until it is no longer running.
+// It returns the final CodeBuild status, which is not STOPPED if the build finished first.
+func (a *App) StopBuild(build *BuildStatus) (codebuildetypes.StatusType, error) {
+	id, err := build.CodeBuildID()
+	if err != nil {
+		return "", err
+	}
+
+	codebuildSvc := codebuild.NewFromConfig(a.Session)
+	getStatus := func() (codebuildetypes.StatusType, error) {
+		resp, err := codebuildSvc.BatchGetBuilds(context.Background(), &codebuild.BatchGetBuildsInput{Ids: []string{id}})
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Builds) == 0 {
+			return "", fmt.Errorf("build %s not found in CodeBuild", id)
+		}
+
+		return resp.Builds[0].BuildStatus, nil
+	}
+
+	status, err := getStatus()
+	if err != nil {
+		return "", err
+	}
+
+	if status != codebuildetypes.StatusTypeInProgress {
+		return "", fmt.Errorf("build #%d is not running in CodeBuild (%s)", build.BuildNumber, strings.ToLower(string(status)))
+	}
+
+	logrus.WithFields(logrus.Fields{"id": id}).Debug("stopping CodeBuild build")
+
+	if _, err = codebuildSvc.StopBuild(context.Background(), &codebuild.StopBuildInput{Id: &id}); err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(buildStopTimeout)
+
+	for status == codebuildetypes.StatusTypeInProgress {
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("timed out waiting for build #%d to stop", build.BuildNumber)
+		}
+
+		time.Sleep(3 * time.Second)
+
+		if status, err = getStatus(); err != nil {
+			return "", err
+		}
+	}
+
+	return status, nil
+}
+
 // ConfigPrefix returns the SSM Parameter Store prefix for config variables
 func (a *App) ConfigPrefix() string {
 	if a.IsReviewApp() {
@@ -932,19 +1007,322 @@ func (a *App) GetConfigWithManaged() (ConfigVariables, error) {
 	return configVars, nil
 }
 
-// SetConfig sets a config value for the app
-func (a *App) SetConfig(key, value string, overwrite bool) error {
-	parameterName := fmt.Sprintf("%s%s", a.ConfigPrefix(), key)
-	ssmSvc := ssm.NewFromConfig(a.Session)
-	parameterType := ssmtypes.ParameterTypeSecureString
-	_, err := ssmSvc.PutParameter(context.Background(), &ssm.PutParameterInput{
-		Name:      &parameterName,
-		Type:      parameterType,
+// GetConfigWithInherited returns the config parameters for the app with managed value populated.
+// Review apps include the variables inherited from their pipeline which they don't override.
+func (a *App) GetConfigWithInherited() (ConfigVariables, error) {
+	configVars, err := a.GetConfigWithManaged()
+	if err != nil || !a.IsReviewApp() {
+		return configVars, err
+	}
+
+	// read the pipeline's config without touching the review app on the shared App
+	pipeline := &App{
+		Name:     a.Name,
+		Pipeline: a.Pipeline,
+		Session:  a.Session,
+		Settings: a.Settings,
+		AWS:      a.AWS,
+	}
+
+	inherited, err := pipeline.GetConfigWithManaged()
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, configVar := range configVars {
+		names[configVar.Name] = true
+	}
+
+	for _, configVar := range inherited {
+		if !names[configVar.Name] {
+			configVars = append(configVars, configVar)
+		}
+	}
+
+	sort.Slice(configVars, func(i, j int) bool {
+		return configVars[i].Name < configVars[j].Name
+	})
+
+	return configVars, nil
+}
+
+// ConfigStorage controls how config values are stored in SSM.
+// The zero value stores a SecureString encrypted with the AWS managed key.
+type ConfigStorage struct {
+	// PlainText stores the value as an unencrypted String
+	PlainText bool
+	// KMSKeyID is the ID, ARN, or alias of the KMS key used to encrypt the value
+	KMSKeyID string
+	// KeepExisting stores values which already exist with their current type and key.
+	// The other fields only apply to new values.
+	KeepExisting bool
+}
+
+// defaultSSMKey is the AWS managed key SSM uses when a SecureString has no KeyId
+const defaultSSMKey = "alias/aws/ssm"
+
+// parameterStorage returns the storage an existing parameter was written with
+func parameterStorage(parameterType ssmtypes.ParameterType, keyID *string) ConfigStorage {
+	if parameterType == ssmtypes.ParameterTypeString {
+		return ConfigStorage{PlainText: true}
+	}
+
+	if id := aws.ToString(keyID); id != defaultSSMKey {
+		return ConfigStorage{KMSKeyID: id}
+	}
+
+	return ConfigStorage{}
+}
+
+// storedConfig is a config value and the storage it was written with
+type storedConfig struct {
+	value   *string
+	storage ConfigStorage
+}
+
+func (s ConfigStorage) putParameterInput(name, value string, overwrite bool) *ssm.PutParameterInput {
+	input := &ssm.PutParameterInput{
+		Name:      &name,
+		Type:      ssmtypes.ParameterTypeSecureString,
 		Overwrite: &overwrite,
 		Value:     &value,
+	}
+
+	if s.PlainText {
+		input.Type = ssmtypes.ParameterTypeString
+	} else if s.KMSKeyID != "" {
+		input.KeyId = &s.KMSKeyID
+	}
+
+	return input
+}
+
+// SetConfig sets a config value for the app
+func (a *App) SetConfig(key, value string, overwrite bool) error {
+	return a.SetConfigWithStorage(key, value, overwrite, ConfigStorage{})
+}
+
+// SetConfigWithStorage sets a config value for the app, stored as described by storage
+func (a *App) SetConfigWithStorage(key, value string, overwrite bool, storage ConfigStorage) error {
+	if storage.PlainText && storage.KMSKeyID != "" {
+		return errors.New("a KMS key can only be used with encrypted values")
+	}
+
+	return a.AWS.PutParameter(storage.putParameterInput(a.ConfigPrefix()+key, value, overwrite))
+}
+
+// SetConfigBatch sets multiple config values for the app, stored as described by storage.
+// If any write fails, the values already written are restored to their previous state.
+func (a *App) SetConfigBatch(values map[string]string, storage ConfigStorage) error {
+	if storage.PlainText && storage.KMSKeyID != "" {
+		return errors.New("a KMS key can only be used with encrypted values")
+	}
+
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		if err := ValidateConfigKey(key); err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	// nil means the variable did not exist before
+	previous := map[string]*storedConfig{}
+
+	for _, key := range keys {
+		stored, err := a.storedConfig(key)
+		if err != nil {
+			return err
+		}
+
+		previous[key] = stored
+	}
+
+	var written []string
+
+	for _, key := range keys {
+		keyStorage := storage
+		if storage.KeepExisting && previous[key] != nil {
+			keyStorage = previous[key].storage
+		}
+
+		err := a.AWS.PutParameter(keyStorage.putParameterInput(a.ConfigPrefix()+key, values[key], true))
+		if err != nil {
+			err = fmt.Errorf("setting %s: %w", key, err)
+
+			return errors.Join(err, a.restoreConfig(written, previous))
+		}
+
+		written = append(written, key)
+	}
+
+	return nil
+}
+
+// storedConfig returns the current value and storage of a config variable, or nil if it doesn't exist
+func (a *App) storedConfig(key string) (*storedConfig, error) {
+	name := a.ConfigPrefix() + key
+
+	value, err := a.AWS.GetParameter(&ssm.GetParameterInput{
+		Name:           &name,
+		WithDecryption: aws.Bool(true),
+	})
+	if err == nil {
+		var metadata *ssmtypes.ParameterMetadata
+
+		metadata, err = a.AWS.DescribeParameter(name)
+		if err == nil {
+			return &storedConfig{value: value, storage: parameterStorage(metadata.Type, metadata.KeyId)}, nil
+		}
+	}
+
+	var notFound *ssmtypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+
+	return nil, err
+}
+
+// restoreConfig puts the keys back to their previous values and storage, removing them if they did not exist
+func (a *App) restoreConfig(keys []string, previous map[string]*storedConfig) error {
+	var errs []error
+
+	for _, key := range keys {
+		logrus.WithFields(logrus.Fields{"key": key}).Debug("rolling back config variable")
+
+		var err error
+		if previous[key] == nil {
+			err = a.AWS.DeleteParameter(&ssm.DeleteParameterInput{
+				Name: aws.String(a.ConfigPrefix() + key),
+			})
+		} else {
+			err = a.AWS.PutParameter(previous[key].storage.putParameterInput(a.ConfigPrefix()+key, *previous[key].value, true))
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rolling back %s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RestoreConfig sets config variables to values, keeping the type and key of those which already exist,
+// then removes the variables in removed. Nothing is removed unless all the values are written.
+// If a removal fails, the error lists the variables which were already changed.
+func (a *App) RestoreConfig(values map[string]string, removed []string) error {
+	if len(values) > 0 {
+		if err := a.SetConfigBatch(values, ConfigStorage{KeepExisting: true}); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+
+	var deleted []string
+
+	for _, key := range removed {
+		if err := a.UnsetConfig(key); err != nil {
+			errs = append(errs, fmt.Errorf("removing %s: %w", key, err))
+		} else {
+			deleted = append(deleted, key)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	written := slices.Sorted(maps.Keys(values))
+
+	return fmt.Errorf("config partially restored (set: %s; removed: %s): %w",
+		noneIfEmpty(written), noneIfEmpty(deleted), errors.Join(errs...))
+}
+
+// noneIfEmpty joins the keys for an error message
+func noneIfEmpty(keys []string) string {
+	if len(keys) == 0 {
+		return "none"
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// UnsetConfig removes a config value for the app
+func (a *App) UnsetConfig(key string) error {
+	return a.AWS.DeleteParameter(&ssm.DeleteParameterInput{
+		Name: aws.String(a.ConfigPrefix() + key),
 	})
+}
+
+// ConfigHistory returns all the stored versions of a config value, oldest first
+func (a *App) ConfigHistory(key string) ([]*ConfigVersion, error) {
+	parameterName := a.ConfigPrefix() + key
 
-	return err
+	logrus.WithFields(logrus.Fields{"parameter": parameterName}).Debug("fetching parameter history")
+
+	history, err := a.AWS.GetParameterHistory(&ssm.GetParameterHistoryInput{
+		Name:           &parameterName,
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConfigVersions(history), nil
+}
+
+// RollbackConfig sets a config value back to the value it had in a previous version
+func (a *App) RollbackConfig(key string, version int64) (*ConfigVersion, error) {
+	history, err := a.ConfigHistory(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := FindConfigVersion(history, version)
+	if v == nil {
+		return nil, fmt.Errorf("version %d of %s not found", version, key)
+	}
+
+	// keep the type and key of the latest version
+	storage := history[len(history)-1].Storage
+
+	return v, a.SetConfigWithStorage(key, v.Value, true, storage)
+}
+
+// ConfigAsOf returns the config variables as they were at the given time.
+// Only variables in current are considered -- SSM discards the history of deleted parameters,
+// so variables removed since then cannot be recovered. Variables created after t are omitted.
+func (a *App) ConfigAsOf(current ConfigVariables, t time.Time) (ConfigVariables, error) {
+	var configVars ConfigVariables
+
+	for _, configVar := range current {
+		history, err := a.ConfigHistory(configVar.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		version := ConfigVersionAsOf(history, t)
+		if version == nil {
+			continue
+		}
+
+		configVars = append(configVars, &ConfigVariable{
+			Name:          configVar.Name,
+			Value:         version.Value,
+			Managed:       configVar.Managed,
+			Type:          configVar.Type,
+			parameterName: configVar.parameterName,
+		})
+	}
+
+	return configVars, nil
 }
 
 // GetConsoleURL generate a URL which will sign the user in to the AWS console and redirect to the desinationURL
@@ -1008,33 +1386,123 @@ func (a *App) DescribeTasks() ([]ecstypes.Task, error) {
 	var appTasks []ecstypes.Task
 
 	for _, task := range describedTasks {
-		isApp := false
-		isReviewApp := false
+		if a.isAppTask(&task) {
+			appTasks = append(appTasks, task)
+		}
+	}
 
-		for _, t := range task.Tags {
-			if *t.Key == "apppack:appName" && *t.Value == a.Name {
-				isApp = true
-			}
+	return appTasks, nil
+}
+
+// isAppTask reports whether the task is tagged as belonging to the app (or review app)
+func (a *App) isAppTask(task *ecstypes.Task) bool {
+	isApp := false
+	isReviewApp := false
 
-			if a.IsReviewApp() {
-				if *t.Key == "apppack:reviewApp" && *t.Value == "pr/"+*a.ReviewApp {
-					isReviewApp = true
-				}
+	for _, t := range task.Tags {
+		if *t.Key == "apppack:appName" && *t.Value == a.Name {
+			isApp = true
+		}
+
+		if a.IsReviewApp() {
+			if *t.Key == "apppack:reviewApp" && *t.Value == "pr/"+*a.ReviewApp {
+				isReviewApp = true
 			}
 		}
+	}
+
+	if a.IsReviewApp() {
+		return isApp && isReviewApp
+	}
+
+	return isApp
+}
 
-		if isApp {
-			if a.IsReviewApp() {
-				if isReviewApp {
-					appTasks = append(appTasks, task)
-				}
-			} else {
-				appTasks = append(appTasks, task)
+// DescribeTask describes one of the app's tasks by ID or ARN.
+// Stopped tasks can be described for a short time after they stop.
+func (a *App) DescribeTask(id string) (*ecstypes.Task, error) {
+	if err := a.LoadSettings(); err != nil {
+		return nil, err
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+
+	logrus.WithFields(logrus.Fields{"task": id}).Debug("fetching task description")
+
+	resp, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+		Tasks:   []string{id},
+		Cluster: &a.Settings.Cluster.ARN,
+		Include: []ecstypes.TaskField{ecstypes.TaskFieldTags},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Tasks) == 0 || !a.isAppTask(&resp.Tasks[0]) {
+		return nil, fmt.Errorf("task %s not found -- ECS only keeps stopped tasks for about an hour", id)
+	}
+
+	return &resp.Tasks[0], nil
+}
+
+// SortTasksNewestFirst sorts tasks by their start time, tasks which haven't started are last
+func SortTasksNewestFirst(tasks []ecstypes.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].StartedAt == nil {
+			return false
+		} else if tasks[j].StartedAt == nil {
+			return true
+		}
+
+		return tasks[i].StartedAt.After(*tasks[j].StartedAt)
+	})
+}
+
+// ParseProcessName splits a process name shown by `apppack ps`, e.g. web.2, into its type and index
+func ParseProcessName(name string) (processType string, index int, err error) {
+	i := strings.LastIndex(name, ".")
+	if i < 1 {
+		return "", 0, fmt.Errorf("invalid process %q -- expected <process type>.<number>, e.g. web.0", name)
+	}
+
+	index, err = strconv.Atoi(name[i+1:])
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("invalid process %q -- expected <process type>.<number>, e.g. web.0", name)
+	}
+
+	return name[:i], index, nil
+}
+
+// ProcessTask returns the running task for a process name shown by `apppack ps`, e.g. web.2.
+// Stopped tasks aren't numbered by `ps`, so they can only be found by task ID with DescribeTask.
+func (a *App) ProcessTask(name string) (*ecstypes.Task, error) {
+	processType, index, err := ParseProcessName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := a.DescribeTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	var processTasks []ecstypes.Task
+
+	for _, task := range tasks {
+		for _, t := range task.Tags {
+			if aws.ToString(t.Key) == "apppack:processType" && aws.ToString(t.Value) == processType {
+				processTasks = append(processTasks, task)
 			}
 		}
 	}
 
-	return appTasks, nil
+	if index >= len(processTasks) {
+		return nil, fmt.Errorf("no running process %s -- %d %s processes are running (stopped processes can only be found by task ID)", name, len(processTasks), processType)
+	}
+
+	SortTasksNewestFirst(processTasks)
+
+	return &processTasks[index], nil
 }
 
 func (a *App) GetECSEvents(service string) ([]ecstypes.ServiceEvent, error) {
@@ -1370,15 +1838,10 @@ func Init(name string, awsCredentials bool, sessionDuration int) (*App, error) {
 		if err != nil {
 			return nil, auth.FriendlyAWSConfigError(err)
 		}
-		app.Session = cfg
-		app.AWS = apppackaws.New(cfg)
 
-		err = app.LoadSettings()
-		if err != nil {
+		if err = app.initWithSession(cfg); err != nil {
 			return nil, err
 		}
-		// this is a horribly hacky way to figure out if the app is a pipeline, but it works
-		app.Pipeline = strings.Contains(app.Settings.StackID, fmt.Sprintf("/apppack-pipeline-%s/", app.Name))
 	} else {
 		cfg, appRole, err := auth.AppAWSSession(name, sessionDuration)
 		if err != nil {
@@ -1396,3 +1859,38 @@ func Init(name string, awsCredentials bool, sessionDuration int) (*App, error) {
 
 	return &app, nil
 }
+
+// InitWithSession creates an App using an existing session, e.g. an admin session for the account
+func InitWithSession(name string, cfg aws.Config) (*App, error) {
+	app := App{Name: name}
+
+	if strings.Contains(name, ":") {
+		parts := strings.Split(name, ":")
+		app.Name = parts[0]
+		app.ReviewApp = &parts[1]
+	}
+
+	if err := app.initWithSession(cfg); err != nil {
+		return nil, err
+	}
+
+	if !app.Pipeline && app.ReviewApp != nil {
+		return nil, fmt.Errorf("%s is a standard app and can't have review apps", app.Name)
+	}
+
+	return &app, nil
+}
+
+// initWithSession sets the session and loads the settings the session can't provide
+func (a *App) initWithSession(cfg aws.Config) error {
+	a.Session = cfg
+	a.AWS = apppackaws.New(cfg)
+
+	if err := a.LoadSettings(); err != nil {
+		return err
+	}
+	// this is a horribly hacky way to figure out if the app is a pipeline, but it works
+	a.Pipeline = strings.Contains(a.Settings.StackID, fmt.Sprintf("/apppack-pipeline-%s/", a.Name))
+
+	return nil
+}
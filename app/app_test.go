@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestParseProcessName(t *testing.T) {
+	t.Parallel()
+
+	processType, index, err := app.ParseProcessName("web.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processType != "web" || index != 2 {
+		t.Errorf("expected web 2, got %s %d", processType, index)
+	}
+
+	processType, index, err = app.ParseProcessName("celery.beat.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processType != "celery.beat" || index != 0 {
+		t.Errorf("expected celery.beat 0, got %s %d", processType, index)
+	}
+
+	for _, name := range []string{"web", ".1", "web.", "web.x", "web.-1"} {
+		if _, _, err := app.ParseProcessName(name); err == nil {
+			t.Errorf("expected error for %q, got nil", name)
+		}
+	}
+}
+
+func TestSortTasksNewestFirst(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tasks := []ecstypes.Task{
+		{TaskArn: aws.String("pending")},
+		{TaskArn: aws.String("old"), StartedAt: aws.Time(now.Add(-time.Hour))},
+		{TaskArn: aws.String("new"), StartedAt: aws.Time(now)},
+	}
+
+	app.SortTasksNewestFirst(tasks)
+
+	for i, expected := range []string{"new", "old", "pending"} {
+		if actual := aws.ToString(tasks[i].TaskArn); actual != expected {
+			t.Errorf("expected %s at %d, got %s", expected, i, actual)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/codebuild"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBatchGetReports is the maximum number of reports CodeBuild describes in a single request
+const maxBatchGetReports = 100
+
+// BuildArtifact is a file CodeBuild stored in S3 for a build
+type BuildArtifact struct {
+	// Name is the artifact identifier from the buildspec, or "primary"
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	// Path is the key relative to the artifact location
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// TestSummary counts the test cases in a report by result
+type TestSummary struct {
+	Total   int32         `json:"total"`
+	Passed  int32         `json:"passed"`
+	Failed  int32         `json:"failed"`
+	Skipped int32         `json:"skipped"`
+	Other   int32         `json:"other"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// CoverageSummary is the coverage measured by a code coverage report
+type CoverageSummary struct {
+	LinePercentage   float64 `json:"line_percentage"`
+	BranchPercentage float64 `json:"branch_percentage"`
+}
+
+// BuildReport is a test or code coverage report CodeBuild generated for a build
+type BuildReport struct {
+	ARN      string           `json:"arn"`
+	Name     string           `json:"name"`
+	Type     string           `json:"type"`
+	Status   string           `json:"status"`
+	Tests    *TestSummary     `json:"tests,omitempty"`
+	Coverage *CoverageSummary `json:"coverage,omitempty"`
+}
+
+// SummarizeTests converts the status counts of a CodeBuild test report into a TestSummary.
+// Errored tests are counted as failed.
+func SummarizeTests(summary *codebuildetypes.TestReportSummary) *TestSummary {
+	if summary == nil {
+		return nil
+	}
+
+	s := TestSummary{
+		Total:   aws.ToInt32(summary.Total),
+		Elapsed: time.Duration(aws.ToInt64(summary.DurationInNanoSeconds)),
+	}
+
+	for status, count := range summary.StatusCounts {
+		switch status {
+		case "SUCCEEDED":
+			s.Passed += count
+		case "FAILED", "ERROR":
+			s.Failed += count
+		case "SKIPPED":
+			s.Skipped += count
+		default:
+			s.Other += count
+		}
+	}
+
+	return &s
+}
+
+// Add adds the counts of another summary to this one
+func (s *TestSummary) Add(other *TestSummary) {
+	s.Total += other.Total
+	s.Passed += other.Passed
+	s.Failed += other.Failed
+	s.Skipped += other.Skipped
+	s.Other += other.Other
+	s.Elapsed += other.Elapsed
+}
+
+// CodeBuildBuild describes the build in CodeBuild
+func (a *App) CodeBuildBuild(build *BuildStatus) (*codebuildetypes.Build, error) {
+	id, err := build.CodeBuildID()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := codebuild.NewFromConfig(a.Session).BatchGetBuilds(context.Background(), &codebuild.BatchGetBuildsInput{Ids: []string{id}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Builds) == 0 {
+		return nil, fmt.Errorf("build %s not found in CodeBuild", id)
+	}
+
+	return &resp.Builds[0], nil
+}
+
+// parseS3ARN splits an S3 ARN, e.g. arn:aws:s3:::bucket/path, into the bucket and key
+func parseS3ARN(s3ARN string) (string, string, error) {
+	parsed, err := arn.Parse(s3ARN)
+	if err != nil || parsed.Service != "s3" {
+		return "", "", fmt.Errorf("invalid S3 ARN %s", s3ARN)
+	}
+
+	bucket, key, _ := strings.Cut(parsed.Resource, "/")
+
+	return bucket, key, nil
+}
+
+// BuildArtifacts lists the files in the build's primary and secondary artifacts
+func (a *App) BuildArtifacts(build *BuildStatus) ([]BuildArtifact, error) {
+	codebuildBuild, err := a.CodeBuildBuild(build)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []codebuildetypes.BuildArtifacts{}
+	if codebuildBuild.Artifacts != nil {
+		locations = append(locations, *codebuildBuild.Artifacts)
+	}
+
+	locations = append(locations, codebuildBuild.SecondaryArtifacts...)
+	s3Svc := s3.NewFromConfig(a.Session)
+	artifacts := []BuildArtifact{}
+
+	for _, location := range locations {
+		if aws.ToString(location.Location) == "" {
+			continue
+		}
+
+		name := aws.ToString(location.ArtifactIdentifier)
+		if name == "" {
+			name = "primary"
+		}
+
+		bucket, prefix, err := parseS3ARN(aws.ToString(location.Location))
+		if err != nil {
+			return nil, err
+		}
+
+		logrus.WithFields(logrus.Fields{"bucket": bucket, "prefix": prefix}).Debug("listing artifacts")
+
+		paginator := s3.NewListObjectsV2Paginator(s3Svc, &s3.ListObjectsV2Input{Bucket: &bucket, Prefix: &prefix})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return nil, err
+			}
+
+			for _, object := range page.Contents {
+				key := aws.ToString(object.Key)
+				path := strings.TrimPrefix(strings.TrimPrefix(key, prefix), "/")
+
+				if path == "" {
+					// the location is a single (zipped) file
+					path = filepath.Base(key)
+				}
+
+				artifacts = append(artifacts, BuildArtifact{
+					Name:   name,
+					Bucket: bucket,
+					Key:    key,
+					Path:   path,
+					Size:   aws.ToInt64(object.Size),
+				})
+			}
+		}
+	}
+
+	return artifacts, nil
+}
+
+// DownloadArtifact saves the artifact in dir under the artifact's name and path, returning the file name
+func (a *App) DownloadArtifact(artifact *BuildArtifact, dir string) (string, error) {
+	relative := filepath.Join(artifact.Name, filepath.FromSlash(artifact.Path))
+	if !filepath.IsLocal(relative) {
+		return "", fmt.Errorf("artifact path %s is outside of %s", artifact.Path, dir)
+	}
+
+	filename := filepath.Join(dir, relative)
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", err
+	}
+
+	logrus.WithFields(logrus.Fields{"bucket": artifact.Bucket, "key": artifact.Key}).Debug("downloading artifact")
+
+	out, err := s3.NewFromConfig(a.Session).GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: &artifact.Bucket,
+		Key:    &artifact.Key,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer out.Body.Close()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = io.Copy(f, out.Body); err != nil {
+		f.Close()
+
+		return "", err
+	}
+
+	return filename, f.Close()
+}
+
+// BuildReports describes the test and code coverage reports CodeBuild generated for the build
+func (a *App) BuildReports(build *BuildStatus) ([]BuildReport, error) {
+	codebuildBuild, err := a.CodeBuildBuild(build)
+	if err != nil {
+		return nil, err
+	}
+
+	codebuildSvc := codebuild.NewFromConfig(a.Session)
+	reports := []BuildReport{}
+
+	for arns := range slices.Chunk(codebuildBuild.ReportArns, maxBatchGetReports) {
+		resp, err := codebuildSvc.BatchGetReports(context.Background(), &codebuild.BatchGetReportsInput{ReportArns: arns})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range resp.Reports {
+			report := &resp.Reports[i]
+			r := BuildReport{
+				ARN:    aws.ToString(report.Arn),
+				Name:   aws.ToString(report.Name),
+				Type:   string(report.Type),
+				Status: string(report.Status),
+				Tests:  SummarizeTests(report.TestSummary),
+			}
+
+			if c := report.CodeCoverageSummary; c != nil {
+				r.Coverage = &CoverageSummary{
+					LinePercentage:   aws.ToFloat64(c.LineCoveragePercentage),
+					BranchPercentage: aws.ToFloat64(c.BranchCoveragePercentage),
+				}
+			}
+
+			reports = append(reports, r)
+		}
+	}
+
+	return reports, nil
+}
+
+// ReportTestCases lists the test cases in a test report. If status is set, only test cases with that status are listed.
+func (a *App) ReportTestCases(reportARN, status string) ([]codebuildetypes.TestCase, error) {
+	input := &codebuild.DescribeTestCasesInput{ReportArn: &reportARN}
+	if status != "" {
+		input.Filter = &codebuildetypes.TestCaseFilter{Status: &status}
+	}
+
+	paginator := codebuild.NewDescribeTestCasesPaginator(codebuild.NewFromConfig(a.Session), input)
+	testCases := []codebuildetypes.TestCase{}
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		testCases = append(testCases, page.TestCases...)
+	}
+
+	return testCases, nil
+}
+
+// reportCoverages lists the per-file coverage in a code coverage report
+func (a *App) reportCoverages(reportARN string) ([]codebuildetypes.CodeCoverage, error) {
+	paginator := codebuild.NewDescribeCodeCoveragesPaginator(codebuild.NewFromConfig(a.Session), &codebuild.DescribeCodeCoveragesInput{ReportArn: &reportARN})
+	coverages := []codebuildetypes.CodeCoverage{}
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		coverages = append(coverages, page.CodeCoverages...)
+	}
+
+	return coverages, nil
+}
+
+// DownloadReportJSON saves the report with its test cases or file coverage, as described by the CodeBuild API,
+// as JSON in dir, returning the file name. It is not the raw report file (e.g. JUnit XML) the build produced.
+func (a *App) DownloadReportJSON(report *BuildReport, dir string) (string, error) {
+	contents := struct {
+		*BuildReport
+		TestCases     []codebuildetypes.TestCase     `json:"test_cases,omitempty"`
+		CodeCoverages []codebuildetypes.CodeCoverage `json:"code_coverages,omitempty"`
+	}{BuildReport: report}
+
+	var err error
+
+	switch codebuildetypes.ReportType(report.Type) {
+	case codebuildetypes.ReportTypeTest:
+		contents.TestCases, err = a.ReportTestCases(report.ARN, "")
+	case codebuildetypes.ReportTypeCodeCoverage:
+		contents.CodeCoverages, err = a.reportCoverages(report.ARN)
+	default:
+		err = errors.New("unknown report type " + report.Type)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	// the report ARN ends in the report group name and the report ID
+	_, id, _ := strings.Cut(report.ARN, ":report/")
+	filename := filepath.Join(dir, "reports-json", strings.NewReplacer("/", "_", ":", "_").Replace(id)+".json")
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(contents, "", JSONIndent)
+	if err != nil {
+		return "", err
+	}
+
+	return filename, os.WriteFile(filename, data, 0o644)
+}
@@ -0,0 +1,30 @@
+package app
+
+import "testing"
+
+func TestParseS3ARN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		arn, bucket, key string
+	}{
+		{"arn:aws:s3:::bucket/path/to/artifacts", "bucket", "path/to/artifacts"},
+		{"arn:aws-cn:s3:::bucket/artifacts.zip", "bucket", "artifacts.zip"},
+		{"arn:aws-us-gov:s3:::bucket", "bucket", ""},
+	}
+
+	for _, tt := range tests {
+		bucket, key, err := parseS3ARN(tt.arn)
+		if err != nil {
+			t.Errorf("%s: %s", tt.arn, err)
+		} else if bucket != tt.bucket || key != tt.key {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.arn, tt.bucket, tt.key, bucket, key)
+		}
+	}
+
+	for _, arn := range []string{"bucket/path", "arn:aws:codebuild:us-east-1:123456789012:build/my-app:1"} {
+		if _, _, err := parseS3ARN(arn); err == nil {
+			t.Errorf("expected error for %s, got nil", arn)
+		}
+	}
+}
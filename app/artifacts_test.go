@@ -0,0 +1,40 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
+)
+
+func TestSummarizeTests(t *testing.T) {
+	t.Parallel()
+
+	summary := app.SummarizeTests(&codebuildetypes.TestReportSummary{
+		Total:                 aws.Int32(10),
+		DurationInNanoSeconds: aws.Int64(int64(2 * time.Second)),
+		StatusCounts: map[string]int32{
+			"SUCCEEDED": 6,
+			"FAILED":    1,
+			"ERROR":     1,
+			"SKIPPED":   1,
+			"UNKNOWN":   1,
+		},
+	})
+
+	expected := app.TestSummary{Total: 10, Passed: 6, Failed: 2, Skipped: 1, Other: 1, Elapsed: 2 * time.Second}
+	if *summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, *summary)
+	}
+
+	summary.Add(&expected)
+	if summary.Total != 20 || summary.Failed != 4 || summary.Elapsed != 4*time.Second {
+		t.Errorf("expected summaries to be added, got %+v", *summary)
+	}
+
+	if app.SummarizeTests(nil) != nil {
+		t.Error("expected nil summary for a report without tests")
+	}
+}
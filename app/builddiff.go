@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sirupsen/logrus"
+)
+
+// TaskDefinitionChange is a setting which differs between the task definitions of a process in two builds
+type TaskDefinitionChange struct {
+	Process string `json:"process"`
+	// Container is empty for task level settings
+	Container string `json:"container,omitempty"`
+	Field     string `json:"field"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// BuildsBetween returns the builds after from up to and including to, newest first
+func (a *App) BuildsBetween(from, to int) ([]BuildStatus, error) {
+	if from >= to {
+		return nil, fmt.Errorf("build #%d is not before build #%d", from, to)
+	}
+
+	primaryID := "APP#" + a.Name
+	if a.IsReviewApp() {
+		primaryID = fmt.Sprintf("%s:%s", primaryID, *a.ReviewApp)
+	}
+
+	logrus.WithFields(logrus.Fields{"from": from, "to": to}).Debug("fetching builds from DDB")
+
+	paginator := dynamodb.NewQueryPaginator(dynamodb.NewFromConfig(a.Session), &dynamodb.QueryInput{
+		TableName:              aws.String("apppack"),
+		KeyConditionExpression: aws.String("primary_id = :id1 AND secondary_id BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id1":  &dynamodbtypes.AttributeValueMemberS{Value: primaryID},
+			":from": &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("BUILD#%010d", from+1)},
+			":to":   &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("BUILD#%010d", to)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+
+	var builds []BuildStatus
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		var i []BuildStatus
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &i); err != nil {
+			return nil, err
+		}
+
+		builds = append(builds, i...)
+	}
+
+	return builds, nil
+}
+
+// DiffTaskDefinitions lists the image, environment variable names, CPU and memory which differ
+// between two task definitions of the process. Containers are matched by name.
+func DiffTaskDefinitions(process string, from, to *ecstypes.TaskDefinition) []TaskDefinitionChange {
+	var changes []TaskDefinitionChange
+
+	add := func(container, field, fromValue, toValue string) {
+		if fromValue != toValue {
+			changes = append(changes, TaskDefinitionChange{Process: process, Container: container, Field: field, From: fromValue, To: toValue})
+		}
+	}
+
+	add("", "cpu", aws.ToString(from.Cpu), aws.ToString(to.Cpu))
+	add("", "memory", aws.ToString(from.Memory), aws.ToString(to.Memory))
+
+	for _, name := range containerNames(from, to) {
+		fromContainer := containerDefinition(from, name)
+		toContainer := containerDefinition(to, name)
+
+		switch {
+		case fromContainer == nil:
+			add(name, "container", "", "added")
+
+			continue
+		case toContainer == nil:
+			add(name, "container", "removed", "")
+
+			continue
+		}
+
+		add(name, "image", aws.ToString(fromContainer.Image), aws.ToString(toContainer.Image))
+		add(name, "cpu", intString(fromContainer.Cpu), intString(toContainer.Cpu))
+		add(name, "memory", int32PtrString(fromContainer.Memory), int32PtrString(toContainer.Memory))
+
+		fromEnv := environmentNames(fromContainer)
+		toEnv := environmentNames(toContainer)
+
+		for _, env := range toEnv {
+			if !slices.Contains(fromEnv, env) {
+				add(name, "environment", "", env)
+			}
+		}
+
+		for _, env := range fromEnv {
+			if !slices.Contains(toEnv, env) {
+				add(name, "environment", env, "")
+			}
+		}
+	}
+
+	return changes
+}
+
+// containerNames returns the names of the containers in either task definition
+func containerNames(taskDefinitions ...*ecstypes.TaskDefinition) []string {
+	var names []string
+
+	for _, taskDefinition := range taskDefinitions {
+		for _, c := range taskDefinition.ContainerDefinitions {
+			if name := aws.ToString(c.Name); !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func containerDefinition(taskDefinition *ecstypes.TaskDefinition, name string) *ecstypes.ContainerDefinition {
+	for i := range taskDefinition.ContainerDefinitions {
+		if aws.ToString(taskDefinition.ContainerDefinitions[i].Name) == name {
+			return &taskDefinition.ContainerDefinitions[i]
+		}
+	}
+
+	return nil
+}
+
+// environmentNames returns the sorted names of the container's environment variables and secrets
+func environmentNames(container *ecstypes.ContainerDefinition) []string {
+	names := make([]string, 0, len(container.Environment)+len(container.Secrets))
+
+	for _, env := range container.Environment {
+		names = append(names, aws.ToString(env.Name))
+	}
+
+	for _, secret := range container.Secrets {
+		names = append(names, aws.ToString(secret.Name))
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+func intString(i int32) string {
+	if i == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(int(i))
+}
+
+func int32PtrString(i *int32) string {
+	if i == nil {
+		return ""
+	}
+
+	return strconv.Itoa(int(*i))
+}
+
+// findBuildTaskDefinition finds the task definition registered by the build, including inactive revisions.
+// It returns nil if the task definition no longer exists.
+func findBuildTaskDefinition(ecsSvc *ecs.Client, family string, buildNumber int) (*ecstypes.TaskDefinition, error) {
+	for _, status := range []ecstypes.TaskDefinitionStatus{ecstypes.TaskDefinitionStatusActive, ecstypes.TaskDefinitionStatusInactive} {
+		taskDefinition, err := buildTaskDefinition(ecsSvc, family, buildNumber, status)
+		if err == nil {
+			return taskDefinition, nil
+		}
+
+		if !errors.Is(err, errNoBuildTaskDefinition) {
+			return nil, err
+		}
+
+		logrus.WithFields(logrus.Fields{"family": family, "status": status, "error": err}).Debug("task definition not found")
+	}
+
+	return nil, nil
+}
+
+// TaskDefinitionChanges lists the differences between the task definitions the two builds registered
+// for each of the app's services
+func (a *App) TaskDefinitionChanges(from, to *BuildStatus) ([]TaskDefinitionChange, error) {
+	if err := a.LoadDeployStatus(); err != nil {
+		return nil, err
+	}
+
+	if a.DeployStatus == nil {
+		return nil, errors.New("the app has not been deployed")
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+
+	var changes []TaskDefinitionChange
+
+	for _, process := range a.DeployStatus.Processes {
+		family := a.ServiceName(process.Name)
+
+		fromTaskDefinition, err := findBuildTaskDefinition(ecsSvc, family, from.BuildNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		toTaskDefinition, err := findBuildTaskDefinition(ecsSvc, family, to.BuildNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		if fromTaskDefinition == nil || toTaskDefinition == nil {
+			changes = append(changes, TaskDefinitionChange{
+				Process: process.Name,
+				Field:   "task definition",
+				From:    taskDefinitionARN(fromTaskDefinition),
+				To:      taskDefinitionARN(toTaskDefinition),
+			})
+
+			continue
+		}
+
+		changes = append(changes, DiffTaskDefinitions(process.Name, fromTaskDefinition, toTaskDefinition)...)
+	}
+
+	return changes, nil
+}
+
+// taskDefinitionARN returns the ARN of the task definition or "unknown" if it wasn't found
+func taskDefinitionARN(taskDefinition *ecstypes.TaskDefinition) string {
+	if taskDefinition == nil {
+		return "unknown"
+	}
+
+	return aws.ToString(taskDefinition.TaskDefinitionArn)
+}
@@ -0,0 +1,56 @@
+package app_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestDiffTaskDefinitions(t *testing.T) {
+	t.Parallel()
+
+	from := &ecstypes.TaskDefinition{
+		Cpu:    aws.String("256"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []ecstypes.ContainerDefinition{
+			{
+				Name:        aws.String("app"),
+				Image:       aws.String("repo:abc"),
+				Environment: []ecstypes.KeyValuePair{{Name: aws.String("DEBUG")}, {Name: aws.String("PORT")}},
+				Secrets:     []ecstypes.Secret{{Name: aws.String("DATABASE_URL")}},
+			},
+			{Name: aws.String("sidecar"), Image: aws.String("proxy:1")},
+		},
+	}
+	to := &ecstypes.TaskDefinition{
+		Cpu:    aws.String("512"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []ecstypes.ContainerDefinition{
+			{
+				Name:        aws.String("app"),
+				Image:       aws.String("repo:def"),
+				Environment: []ecstypes.KeyValuePair{{Name: aws.String("PORT")}, {Name: aws.String("WORKERS")}},
+				Secrets:     []ecstypes.Secret{{Name: aws.String("DATABASE_URL")}},
+			},
+		},
+	}
+
+	expected := []app.TaskDefinitionChange{
+		{Process: "web", Field: "cpu", From: "256", To: "512"},
+		{Process: "web", Container: "app", Field: "image", From: "repo:abc", To: "repo:def"},
+		{Process: "web", Container: "app", Field: "environment", From: "", To: "WORKERS"},
+		{Process: "web", Container: "app", Field: "environment", From: "DEBUG", To: ""},
+		{Process: "web", Container: "sidecar", Field: "container", From: "removed", To: ""},
+	}
+
+	if changes := app.DiffTaskDefinitions("web", from, to); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+
+	if changes := app.DiffTaskDefinitions("web", from, from); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
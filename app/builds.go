@@ -1,12 +1,17 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codebuild"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
 )
 
 // DetermineBuildSourceVersion returns the appropriate SourceVersion for CodeBuild
@@ -22,6 +27,112 @@ func DetermineBuildSourceVersion(isReviewApp bool, reviewAppNumber *string, ref
 	return nil
 }
 
+const (
+	// SkipTestsVariable is set in the build environment to ask the build to skip the test phase
+	SkipTestsVariable = "APPPACK_SKIP_TESTS"
+	// LocalBuildVariable is set in the build environment of builds started from a local source archive
+	LocalBuildVariable = "APPPACK_LOCAL_BUILD"
+)
+
+// BuildOptions are the overrides for a single build
+type BuildOptions struct {
+	// Ref is the git reference to build instead of the branch defined in AppPack
+	Ref string
+	// Env are extra environment variables for the build
+	Env       map[string]string
+	NoCache   bool
+	SkipTests bool
+	// SourceLocation is the bucket/key of a source archive in S3 to build instead of the git repository
+	SourceLocation string
+}
+
+// ParseBuildEnv parses KEY=value pairs into a map. Later pairs override earlier ones.
+func ParseBuildEnv(pairs []string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid environment variable %q -- expected KEY=value", pair)
+		}
+
+		if !configKeyRe.MatchString(key) {
+			return nil, fmt.Errorf("invalid environment variable name %q", key)
+		}
+
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// BuildEnvironmentOverrides returns the environment variables to override for the build, sorted by name.
+// The variables AppPack sets itself take precedence over the ones in the options.
+func BuildEnvironmentOverrides(options *BuildOptions, reviewAppCreated bool) []codebuildetypes.EnvironmentVariable {
+	env := maps.Clone(options.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	if options.SkipTests {
+		env[SkipTestsVariable] = "1"
+	}
+
+	if options.SourceLocation != "" {
+		env[LocalBuildVariable] = "1"
+	}
+
+	if reviewAppCreated {
+		env["REVIEW_APP_STATUS"] = "created"
+	}
+
+	overrides := make([]codebuildetypes.EnvironmentVariable, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		overrides = append(overrides, codebuildetypes.EnvironmentVariable{
+			Name:  aws.String(name),
+			Value: aws.String(env[name]),
+			Type:  codebuildetypes.EnvironmentVariableTypePlaintext,
+		})
+	}
+
+	return overrides
+}
+
+// ProjectSupportsSkipTests reports whether the CodeBuild project reads SkipTestsVariable,
+// either in its buildspec or by setting a default for it. A buildspec stored in the
+// repository can't be checked, so it is reported as unsupported.
+func ProjectSupportsSkipTests(project *codebuildetypes.Project) bool {
+	if project.Environment != nil {
+		for _, v := range project.Environment.EnvironmentVariables {
+			if aws.ToString(v.Name) == SkipTestsVariable {
+				return true
+			}
+		}
+	}
+
+	return project.Source != nil && strings.Contains(aws.ToString(project.Source.Buildspec), SkipTestsVariable)
+}
+
+// codebuildProject returns the app's CodeBuild project
+func (a *App) codebuildProject() (*codebuildetypes.Project, error) {
+	if err := a.LoadSettings(); err != nil {
+		return nil, err
+	}
+
+	resp, err := codebuild.NewFromConfig(a.Session).BatchGetProjects(context.Background(), &codebuild.BatchGetProjectsInput{
+		Names: []string{a.Settings.CodebuildProject.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Projects) == 0 {
+		return nil, fmt.Errorf("CodeBuild project %s not found", a.Settings.CodebuildProject.Name)
+	}
+
+	return &resp.Projects[0], nil
+}
+
 type BuildPhaseDetail struct {
 	Arns  []string `dynamodbav:"arns"  json:"arns"`
 	Logs  string   `dynamodbav:"logs"  json:"logs"`
@@ -55,6 +166,8 @@ type BuildStatus struct {
 	Release     BuildPhaseDetail `dynamodbav:"release"      json:"release"`
 	Postdeploy  BuildPhaseDetail `dynamodbav:"postdeploy"   json:"postdeploy"`
 	Deploy      BuildPhaseDetail `dynamodbav:"deploy"       json:"deploy"`
+	// Local is set for builds of a local source archive, see MarkLocalBuild
+	Local bool `dynamodbav:"local" json:"-"`
 }
 
 type BuildPhase struct {
@@ -128,6 +241,25 @@ func (b *BuildStatus) FinalPhase() (*BuildPhase, error) {
 	return nil, errors.New("no phases completed")
 }
 
+// CodeBuildID returns the ID of the build in CodeBuild, e.g. my-app:0a1b2c3d-...
+func (b *BuildStatus) CodeBuildID() (string, error) {
+	if len(b.Build.Arns) == 0 {
+		return "", errors.New("build has not started yet -- try again in a few seconds")
+	}
+
+	_, id, found := strings.Cut(b.Build.Arns[0], "/")
+	if !found {
+		return "", fmt.Errorf("invalid CodeBuild ARN %s", b.Build.Arns[0])
+	}
+
+	return id, nil
+}
+
+// DeployStarted reports whether the build has reached the phases which change the running app
+func (b *BuildStatus) DeployStarted() bool {
+	return b.Release.State != "" || b.Postdeploy.State != "" || b.Deploy.State != ""
+}
+
 func (b *BuildStatus) FirstFailedPhase() *BuildPhase {
 	for _, p := range b.NamedPhases() {
 		if p.Phase.State == PhaseFailed {
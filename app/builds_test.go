@@ -1,10 +1,12 @@
 package app_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/apppackio/apppack/app"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
 )
 
 func TestDetermineBuildSourceVersion(t *testing.T) {
@@ -81,3 +83,128 @@ func TestDetermineBuildSourceVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildStatusCodeBuildID(t *testing.T) {
+	t.Parallel()
+
+	build := app.BuildStatus{Build: app.BuildPhaseDetail{
+		Arns: []string{"arn:aws:codebuild:us-east-1:123456789012:build/my-app:0a1b2c3d-4e5f"},
+	}}
+
+	id, err := build.CodeBuildID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "my-app:0a1b2c3d-4e5f" {
+		t.Errorf("expected my-app:0a1b2c3d-4e5f, got %s", id)
+	}
+
+	if _, err := (&app.BuildStatus{}).CodeBuildID(); err == nil {
+		t.Error("expected error for a build which hasn't started, got nil")
+	}
+}
+
+func TestBuildStatusDeployStarted(t *testing.T) {
+	t.Parallel()
+
+	build := app.BuildStatus{
+		Build:    app.BuildPhaseDetail{State: app.PhaseSuccess},
+		Test:     app.BuildPhaseDetail{State: app.PhaseSuccess},
+		Finalize: app.BuildPhaseDetail{State: app.PhaseInProgress},
+	}
+
+	if build.DeployStarted() {
+		t.Error("expected deploy not to have started during finalize")
+	}
+
+	build.Release.State = app.PhaseInProgress
+
+	if !build.DeployStarted() {
+		t.Error("expected deploy to have started during release")
+	}
+}
+
+func TestParseBuildEnv(t *testing.T) {
+	t.Parallel()
+
+	env, err := app.ParseBuildEnv([]string{"SKIP_ASSETS=1", "GREETING=a=b", "SKIP_ASSETS=0", "EMPTY="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"SKIP_ASSETS": "0", "GREETING": "a=b", "EMPTY": ""}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+
+	for _, pair := range []string{"SKIP_ASSETS", "=1", "1X=1"} {
+		if _, err := app.ParseBuildEnv([]string{pair}); err == nil {
+			t.Errorf("expected error for %q, got nil", pair)
+		}
+	}
+}
+
+func TestBuildEnvironmentOverrides(t *testing.T) {
+	t.Parallel()
+
+	options := &app.BuildOptions{
+		Env:       map[string]string{"SKIP_ASSETS": "1", "REVIEW_APP_STATUS": "ignored"},
+		SkipTests: true,
+	}
+
+	overrides := app.BuildEnvironmentOverrides(options, true)
+
+	actual := map[string]string{}
+	names := []string{}
+
+	for _, o := range overrides {
+		actual[aws.ToString(o.Name)] = aws.ToString(o.Value)
+		names = append(names, aws.ToString(o.Name))
+	}
+
+	expected := map[string]string{"APPPACK_SKIP_TESTS": "1", "REVIEW_APP_STATUS": "created", "SKIP_ASSETS": "1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	if !reflect.DeepEqual(names, []string{"APPPACK_SKIP_TESTS", "REVIEW_APP_STATUS", "SKIP_ASSETS"}) {
+		t.Errorf("expected overrides sorted by name, got %v", names)
+	}
+
+	if overrides := app.BuildEnvironmentOverrides(&app.BuildOptions{}, false); len(overrides) != 0 {
+		t.Errorf("expected no overrides, got %v", overrides)
+	}
+
+	overrides = app.BuildEnvironmentOverrides(&app.BuildOptions{SourceLocation: "bucket/source.zip"}, false)
+	if len(overrides) != 1 || aws.ToString(overrides[0].Name) != app.LocalBuildVariable {
+		t.Errorf("expected local builds to be marked with %s, got %v", app.LocalBuildVariable, overrides)
+	}
+
+	if options.Env["REVIEW_APP_STATUS"] != "ignored" {
+		t.Error("expected options not to be modified")
+	}
+}
+
+func TestProjectSupportsSkipTests(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		project  codebuildetypes.Project
+		expected bool
+	}{
+		{"no source", codebuildetypes.Project{}, false},
+		{"buildspec in repo", codebuildetypes.Project{Source: &codebuildetypes.ProjectSource{Buildspec: aws.String("buildspec.yml")}}, false},
+		{"inline buildspec", codebuildetypes.Project{Source: &codebuildetypes.ProjectSource{Buildspec: aws.String(`if [ -z "$APPPACK_SKIP_TESTS" ]; then ./test.sh; fi`)}}, true},
+		{"default variable", codebuildetypes.Project{Environment: &codebuildetypes.ProjectEnvironment{
+			EnvironmentVariables: []codebuildetypes.EnvironmentVariable{{Name: aws.String(app.SkipTestsVariable), Value: aws.String("")}},
+		}}, true},
+	}
+
+	for _, tt := range tests {
+		if actual := app.ProjectSupportsSkipTests(&tt.project); actual != tt.expected {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.expected, actual)
+		}
+	}
+}
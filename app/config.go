@@ -3,9 +3,12 @@ package app
 import (
 	"bytes"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/juju/ansiterm"
@@ -16,9 +19,15 @@ type ConfigVariable struct {
 	Name          string
 	Value         string
 	Managed       bool
+	Type          ssmtypes.ParameterType
 	parameterName string
 }
 
+// IsPlainText reports whether the variable is stored unencrypted
+func (v *ConfigVariable) IsPlainText() bool {
+	return v.Type == ssmtypes.ParameterTypeString || v.Type == ssmtypes.ParameterTypeStringList
+}
+
 // LoadManaged loads the Managed value for the ConfigVariable from SSM tags
 func (v *ConfigVariable) LoadManaged(ssmListTagsForResource func(*ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error)) error {
 	logrus.WithFields(logrus.Fields{"parameter": v.parameterName}).Debug("loading parameter tag")
@@ -57,6 +66,7 @@ func NewConfigVariables(parameters []ssmtypes.Parameter) ConfigVariables {
 		configVars = append(configVars, &ConfigVariable{
 			Name:          name,
 			Value:         *parameter.Value,
+			Type:          parameter.Type,
 			parameterName: *parameter.Name,
 		})
 	}
@@ -113,9 +123,252 @@ func printRow(w *ansiterm.TabWriter, name, value string) {
 	fmt.Fprintf(w, "\t%s\n", value)
 }
 
-// ToConsole prints the config vars to the console via the TabWriter
+// ToConsole prints the config vars to the console via the TabWriter.
+// Variables which are not encrypted are flagged.
 func (a *ConfigVariables) ToConsole(w *ansiterm.TabWriter) {
 	for _, configVar := range *a {
-		printRow(w, configVar.Name, configVar.Value)
+		if !configVar.IsPlainText() {
+			printRow(w, configVar.Name, configVar.Value)
+
+			continue
+		}
+
+		w.SetForeground(ansiterm.Green)
+		fmt.Fprintf(w, "%s:", configVar.Name)
+		w.SetForeground(ansiterm.Default)
+		fmt.Fprintf(w, "\t%s\t", configVar.Value)
+		w.SetForeground(ansiterm.Yellow)
+		fmt.Fprint(w, "(not encrypted)\n")
+		w.SetForeground(ansiterm.Default)
+	}
+}
+
+// PlainText returns the names of the variables which are not encrypted
+func (a *ConfigVariables) PlainText() []string {
+	var names []string
+
+	for _, configVar := range *a {
+		if configVar.IsPlainText() {
+			names = append(names, configVar.Name)
+		}
+	}
+
+	return names
+}
+
+// maxConfigKeyLength leaves room for the longest ConfigPrefix in the 1011 character SSM parameter name limit
+const maxConfigKeyLength = 900
+
+// ValidateConfigKey checks that key can be used as a config variable name
+func ValidateConfigKey(key string) error {
+	if !configKeyRe.MatchString(key) {
+		return fmt.Errorf("invalid config variable name %q -- must start with a letter or underscore and only contain letters, numbers, \"_\", \".\", and \"-\"", key)
+	}
+
+	if len(key) > maxConfigKeyLength {
+		return fmt.Errorf("config variable name %q is too long", key)
+	}
+
+	return nil
+}
+
+// Unmanaged returns only the config variables which are not managed by AppPack
+func (a *ConfigVariables) Unmanaged() ConfigVariables {
+	var configVars ConfigVariables
+
+	for _, configVar := range *a {
+		if !configVar.Managed {
+			configVars = append(configVars, configVar)
+		}
+	}
+
+	return configVars
+}
+
+// matchesAny reports whether the name matches one of the glob patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the config variables matching one of the only patterns (all if empty)
+// and none of the except patterns. Patterns may use glob syntax, e.g. AWS_*.
+func (a *ConfigVariables) Filter(only, except []string) ConfigVariables {
+	var configVars ConfigVariables
+
+	for _, configVar := range *a {
+		if len(only) > 0 && !matchesAny(configVar.Name, only) {
+			continue
+		}
+
+		if matchesAny(configVar.Name, except) {
+			continue
+		}
+
+		configVars = append(configVars, configVar)
 	}
+
+	return configVars
+}
+
+const (
+	ConfigAdded   = "added"
+	ConfigRemoved = "removed"
+	ConfigChanged = "changed"
+)
+
+// MaskedValue is displayed in place of a config value which should not be shown
+const MaskedValue = "********"
+
+// SensitiveConfigPatterns match config variable names which are always masked unless explicitly revealed
+var SensitiveConfigPatterns = []string{"DATABASE_URL", "*_SECRET", "*_TOKEN", "*_KEY", "*_PASSWORD"}
+
+// IsSensitiveConfigKey reports whether the name matches one of the SensitiveConfigPatterns
+func IsSensitiveConfigKey(name string) bool {
+	return matchesAny(strings.ToUpper(name), SensitiveConfigPatterns)
+}
+
+// Masked returns a copy of the config variables with values masked.
+// revealAll shows every value except sensitive ones, which are only shown if they are in reveal.
+func (a *ConfigVariables) Masked(revealAll bool, reveal []string) ConfigVariables {
+	configVars := make(ConfigVariables, 0, len(*a))
+
+	for _, configVar := range *a {
+		masked := *configVar
+
+		explicit := false
+
+		for _, name := range reveal {
+			if name == configVar.Name {
+				explicit = true
+			}
+		}
+
+		if !explicit && (!revealAll || IsSensitiveConfigKey(configVar.Name)) {
+			masked.Value = MaskedValue
+		}
+
+		configVars = append(configVars, &masked)
+	}
+
+	return configVars
+}
+
+// ConfigDifference is a single config variable that differs between two sets of config variables
+type ConfigDifference struct {
+	Name     string
+	Change   string
+	OldValue string
+	NewValue string
+}
+
+// Diff compares the config variables to other and returns the differences sorted by name.
+// Variables only in other are added, variables missing from other are removed.
+func (a *ConfigVariables) Diff(other ConfigVariables) []*ConfigDifference {
+	current := map[string]string{}
+	for _, configVar := range *a {
+		current[configVar.Name] = configVar.Value
+	}
+
+	var diffs []*ConfigDifference
+
+	seen := map[string]bool{}
+
+	for _, configVar := range other {
+		seen[configVar.Name] = true
+
+		oldValue, ok := current[configVar.Name]
+		if !ok {
+			diffs = append(diffs, &ConfigDifference{Name: configVar.Name, Change: ConfigAdded, NewValue: configVar.Value})
+		} else if oldValue != configVar.Value {
+			diffs = append(diffs, &ConfigDifference{Name: configVar.Name, Change: ConfigChanged, OldValue: oldValue, NewValue: configVar.Value})
+		}
+	}
+
+	for _, configVar := range *a {
+		if !seen[configVar.Name] {
+			diffs = append(diffs, &ConfigDifference{Name: configVar.Name, Change: ConfigRemoved, OldValue: configVar.Value})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
+
+// ConfigVersion is a single version from the history of a config variable
+type ConfigVersion struct {
+	Version          int64
+	Value            string
+	LastModifiedDate time.Time
+	LastModifiedUser string
+	// Storage is how the version was stored
+	Storage ConfigStorage
+}
+
+// NewConfigVersions creates a list of ConfigVersion from the provided SSM parameter history, sorted oldest first
+func NewConfigVersions(history []ssmtypes.ParameterHistory) []*ConfigVersion {
+	versions := make([]*ConfigVersion, 0, len(history))
+
+	for i := range history {
+		v := ConfigVersion{
+			Version:          history[i].Version,
+			Value:            aws.ToString(history[i].Value),
+			LastModifiedUser: aws.ToString(history[i].LastModifiedUser),
+			Storage:          parameterStorage(history[i].Type, history[i].KeyId),
+		}
+		if history[i].LastModifiedDate != nil {
+			v.LastModifiedDate = *history[i].LastModifiedDate
+		}
+
+		versions = append(versions, &v)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions
+}
+
+// ModifiedBy returns the user who created the version.
+// For federated users this is the role session name (their email address) from the assumed role ARN.
+func (v *ConfigVersion) ModifiedBy() string {
+	parts := strings.Split(v.LastModifiedUser, "/")
+
+	return parts[len(parts)-1]
+}
+
+// ConfigVersionAsOf returns the version which was current at the given time
+// or nil if the variable did not exist yet. history must be sorted oldest first.
+func ConfigVersionAsOf(history []*ConfigVersion, t time.Time) *ConfigVersion {
+	var current *ConfigVersion
+
+	for _, v := range history {
+		if v.LastModifiedDate.After(t) {
+			break
+		}
+
+		current = v
+	}
+
+	return current
+}
+
+// FindConfigVersion returns the version with the number from the history, or nil if it isn't found
+func FindConfigVersion(history []*ConfigVersion, version int64) *ConfigVersion {
+	for _, v := range history {
+		if v.Version == version {
+			return v
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ConfigChangeSet   = "set"
+	ConfigChangeUnset = "unset"
+)
+
+// configAuditEvents are the CloudTrail event names which modify config variables
+var configAuditEvents = map[string]string{
+	"PutParameter":    ConfigChangeSet,
+	"DeleteParameter": ConfigChangeUnset,
+}
+
+// maxConfigAuditPages caps the CloudTrail pages scanned for each event name when auditing every variable.
+// Event name lookups return the changes to every parameter in the account, so they can be slow.
+const maxConfigAuditPages = 20
+
+// ConfigChange is a modification of a config variable recorded in CloudTrail
+type ConfigChange struct {
+	Name      string
+	Action    string
+	Time      time.Time
+	User      string
+	Version   int64
+	EventID   string
+	SourceIP  string
+	UserAgent string
+}
+
+// cloudTrailEvent is the subset of the CloudTrail event record needed for a ConfigChange
+type cloudTrailEvent struct {
+	EventName    string `json:"eventName"`
+	ErrorCode    string `json:"errorCode"`
+	SourceIP     string `json:"sourceIPAddress"`
+	UserAgent    string `json:"userAgent"`
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+	RequestParameters struct {
+		Name string `json:"name"`
+	} `json:"requestParameters"`
+	ResponseElements struct {
+		Version int64 `json:"version"`
+	} `json:"responseElements"`
+}
+
+// NewConfigChange creates a ConfigChange from a CloudTrail event.
+// It returns nil if the event is not a successful change to a variable under prefix.
+func NewConfigChange(event *cloudtrailtypes.Event, prefix string) (*ConfigChange, error) {
+	record := cloudTrailEvent{}
+	if err := json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &record); err != nil {
+		return nil, err
+	}
+
+	action, ok := configAuditEvents[record.EventName]
+	if !ok || record.ErrorCode != "" || !strings.HasPrefix(record.RequestParameters.Name, prefix) {
+		return nil, nil
+	}
+
+	// for federated users, the role session name is their email address
+	user := aws.ToString(event.Username)
+	if user == "" {
+		parts := strings.Split(record.UserIdentity.ARN, "/")
+		user = parts[len(parts)-1]
+	}
+
+	return &ConfigChange{
+		Name:      strings.TrimPrefix(record.RequestParameters.Name, prefix),
+		Action:    action,
+		Time:      aws.ToTime(event.EventTime),
+		User:      user,
+		Version:   record.ResponseElements.Version,
+		EventID:   aws.ToString(event.EventId),
+		SourceIP:  record.SourceIP,
+		UserAgent: record.UserAgent,
+	}, nil
+}
+
+// configAuditLookups returns the CloudTrail lookups which find the changes to the parameters under prefix.
+// A single variable is looked up by its parameter name, otherwise each event name is looked up.
+func configAuditLookups(prefix, name string) []cloudtrailtypes.LookupAttribute {
+	if name != "" {
+		return []cloudtrailtypes.LookupAttribute{{
+			AttributeKey:   cloudtrailtypes.LookupAttributeKeyResourceName,
+			AttributeValue: aws.String(prefix + name),
+		}}
+	}
+
+	lookups := make([]cloudtrailtypes.LookupAttribute, 0, len(configAuditEvents))
+	for eventName := range configAuditEvents {
+		lookups = append(lookups, cloudtrailtypes.LookupAttribute{
+			AttributeKey:   cloudtrailtypes.LookupAttributeKeyEventName,
+			AttributeValue: aws.String(eventName),
+		})
+	}
+
+	sort.Slice(lookups, func(i, j int) bool {
+		return aws.ToString(lookups[i].AttributeValue) < aws.ToString(lookups[j].AttributeValue)
+	})
+
+	return lookups
+}
+
+// ConfigAudit returns the changes to the app's config variables (or only the named variable) since the given time, newest first.
+// When auditing every variable, only the most recent events are scanned and truncated reports whether older events were skipped.
+func (a *App) ConfigAudit(name string, since time.Time) (changes []*ConfigChange, truncated bool, err error) {
+	prefix := a.ConfigPrefix()
+	cloudtrailSvc := cloudtrail.NewFromConfig(a.Session)
+
+	for _, lookup := range configAuditLookups(prefix, name) {
+		paginator := cloudtrail.NewLookupEventsPaginator(cloudtrailSvc, &cloudtrail.LookupEventsInput{
+			LookupAttributes: []cloudtrailtypes.LookupAttribute{lookup},
+			StartTime:        &since,
+		})
+
+		for pages := 0; paginator.HasMorePages(); pages++ {
+			if name == "" && pages >= maxConfigAuditPages {
+				truncated = true
+
+				break
+			}
+
+			logrus.WithFields(logrus.Fields{"lookup": aws.ToString(lookup.AttributeValue)}).Debug("looking up CloudTrail events")
+
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return nil, false, err
+			}
+
+			for i := range page.Events {
+				change, err := NewConfigChange(&page.Events[i], prefix)
+				if err != nil {
+					return nil, false, err
+				}
+
+				if change != nil && (name == "" || change.Name == name) {
+					changes = append(changes, change)
+				}
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Time.After(changes[j].Time)
+	})
+
+	return changes, truncated, nil
+}
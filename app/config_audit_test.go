@@ -0,0 +1,60 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+func TestNewConfigChange(t *testing.T) {
+	t.Parallel()
+
+	prefix := "/apppack/apps/myapp/config/"
+	eventTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	change, err := app.NewConfigChange(&cloudtrailtypes.Event{
+		EventId:   aws.String("abc"),
+		EventTime: &eventTime,
+		CloudTrailEvent: aws.String(`{
+			"eventName": "PutParameter",
+			"userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/apppack-app-myapp-role/user@example.com"},
+			"requestParameters": {"name": "/apppack/apps/myapp/config/SECRET_KEY", "type": "SecureString", "overwrite": true},
+			"responseElements": {"version": 3, "tier": "Standard"}
+		}`),
+	}, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := app.ConfigChange{
+		Name:    "SECRET_KEY",
+		Action:  app.ConfigChangeSet,
+		Time:    eventTime,
+		User:    "user@example.com",
+		Version: 3,
+		EventID: "abc",
+	}
+	if change == nil || *change != expected {
+		t.Errorf("expected %+v, got %+v", expected, change)
+	}
+
+	ignored := []string{
+		`{"eventName": "PutParameter", "requestParameters": {"name": "/apppack/apps/otherapp/config/SECRET_KEY"}}`,
+		`{"eventName": "PutParameter", "errorCode": "AccessDenied", "requestParameters": {"name": "/apppack/apps/myapp/config/SECRET_KEY"}}`,
+		`{"eventName": "GetParameter", "requestParameters": {"name": "/apppack/apps/myapp/config/SECRET_KEY"}}`,
+	}
+
+	for _, record := range ignored {
+		change, err := app.NewConfigChange(&cloudtrailtypes.Event{CloudTrailEvent: aws.String(record)}, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if change != nil {
+			t.Errorf("expected nil for %s, got %+v", record, change)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatDotenv = "dotenv"
+	FormatShell  = "shell"
+	FormatYAML   = "yaml"
+)
+
+// ConfigFormats are the supported formats for exporting and importing config variables
+var ConfigFormats = []string{FormatJSON, FormatDotenv, FormatShell, FormatYAML}
+
+// plainTextKey lists the variables which are stored unencrypted in dotenv and shell files written with types
+const plainTextKey = "apppack:plaintext"
+
+// typedConfig is the document written by ToFormatWithTypes for JSON and YAML.
+// Type information is kept beside the values so it is never mistaken for a variable.
+type typedConfig struct {
+	Config    map[string]string `json:"config"              yaml:"config"`
+	PlainText []string          `json:"plaintext,omitempty" yaml:"plaintext,omitempty"`
+}
+
+var (
+	configKeyRe     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+	unquotedValueRe = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=%\-]*$`)
+)
+
+// ToMap returns the config variables as a map of name to value
+func (a *ConfigVariables) ToMap() map[string]string {
+	results := map[string]string{}
+
+	for _, configVar := range *a {
+		results[configVar.Name] = configVar.Value
+	}
+
+	return results
+}
+
+// ToFormat returns a representation of the config variables in one of the ConfigFormats
+func (a *ConfigVariables) ToFormat(format string) (*bytes.Buffer, error) {
+	return EncodeConfig(a.ToMap(), format)
+}
+
+// ToFormatWithTypes is like ToFormat but also records which variables are not encrypted.
+// JSON and YAML are written as {"config": {...}, "plaintext": [...]}; dotenv and shell
+// files list the plain text variables in a comment.
+func (a *ConfigVariables) ToFormatWithTypes(format string) (*bytes.Buffer, error) {
+	values := a.ToMap()
+	plainText := a.PlainText()
+
+	switch format {
+	case FormatDotenv, FormatShell:
+		buf, err := EncodeConfig(values, format)
+		if err != nil || len(plainText) == 0 {
+			return buf, err
+		}
+
+		return bytes.NewBufferString(fmt.Sprintf("# %s=%s\n%s", plainTextKey, strings.Join(plainText, ","), buf)), nil
+	case FormatJSON:
+		return toJSON(typedConfig{Config: values, PlainText: plainText})
+	case FormatYAML:
+		out, err := yaml.Marshal(typedConfig{Config: values, PlainText: plainText})
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.NewBuffer(bytes.TrimSuffix(out, []byte("\n"))), nil
+	default:
+		return nil, unknownFormatError(format)
+	}
+}
+
+// EncodeConfig serializes config values to one of the ConfigFormats
+func EncodeConfig(values map[string]string, format string) (*bytes.Buffer, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	switch format {
+	case FormatJSON:
+		return toJSON(values)
+	case FormatDotenv:
+		buf := &bytes.Buffer{}
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s=%s\n", key, dotenvQuote(values[key]))
+		}
+
+		return bytes.NewBuffer(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+	case FormatShell:
+		buf := &bytes.Buffer{}
+		for _, key := range keys {
+			fmt.Fprintf(buf, "export %s=%s\n", key, shellQuote(values[key]))
+		}
+
+		return bytes.NewBuffer(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+	case FormatYAML:
+		if len(values) == 0 {
+			return bytes.NewBufferString("{}"), nil
+		}
+
+		out, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.NewBuffer(bytes.TrimSuffix(out, []byte("\n"))), nil
+	default:
+		return nil, unknownFormatError(format)
+	}
+}
+
+// DecodeConfig parses config values from one of the ConfigFormats.
+// Parse errors include the line number where the problem was found.
+func DecodeConfig(data []byte, format string) (map[string]string, error) {
+	switch format {
+	case FormatJSON:
+		values := map[string]string{}
+		if err := unmarshalJSON(data, &values); err != nil {
+			return nil, err
+		}
+
+		return values, nil
+	case FormatDotenv:
+		return decodeEnv(data, false)
+	case FormatShell:
+		return decodeEnv(data, true)
+	case FormatYAML:
+		values := map[string]string{}
+		// yaml errors already include the line number
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+
+		return values, nil
+	default:
+		return nil, unknownFormatError(format)
+	}
+}
+
+// DecodeConfigWithTypes parses config values written by ToFormatWithTypes
+// and returns the names of the variables which are not encrypted.
+func DecodeConfigWithTypes(data []byte, format string) (map[string]string, []string, error) {
+	var config typedConfig
+
+	switch format {
+	case FormatDotenv, FormatShell:
+		values, err := DecodeConfig(data, format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		config.Config = values
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if after, found := strings.CutPrefix(strings.TrimSpace(line), "# "+plainTextKey+"="); found {
+				config.PlainText = strings.Split(after, ",")
+			}
+		}
+	case FormatJSON:
+		if err := unmarshalJSON(data, &config); err != nil {
+			return nil, nil, err
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, unknownFormatError(format)
+	}
+
+	if config.Config == nil {
+		return nil, nil, errors.New(`missing "config" -- was the file exported with --with-types?`)
+	}
+
+	var plainText []string
+
+	for _, name := range config.PlainText {
+		if _, ok := config.Config[strings.TrimSpace(name)]; ok {
+			plainText = append(plainText, strings.TrimSpace(name))
+		}
+	}
+
+	return config.Config, plainText, nil
+}
+
+// unmarshalJSON decodes JSON, adding the line number of the problem to errors
+func unmarshalJSON(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("line %d: %w", lineAtOffset(data, syntaxErr.Offset), err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("line %d: %w", lineAtOffset(data, typeErr.Offset), err)
+	}
+
+	return err
+}
+
+func unknownFormatError(format string) error {
+	return fmt.Errorf("unknown format %q -- must be one of %s", format, strings.Join(ConfigFormats, ", "))
+}
+
+// lineAtOffset returns the 1-indexed line number of the byte offset in data
+func lineAtOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// dotenvQuote returns the value quoted for a dotenv file, if necessary
+func dotenvQuote(value string) string {
+	if unquotedValueRe.MatchString(value) {
+		return value
+	}
+
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"$", `\$`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// shellQuote returns the value quoted for a POSIX shell, if necessary
+func shellQuote(value string) string {
+	if value != "" && unquotedValueRe.MatchString(value) {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// decodeEnv parses a dotenv file or shell script of `export KEY=value` lines.
+// In shell mode, values follow POSIX quoting rules. In dotenv mode, unquoted
+// values run to the end of the line and double-quoted values support escape sequences.
+func decodeEnv(data []byte, shell bool) (map[string]string, error) {
+	values := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		}
+
+		key, rest, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected <variable>=<value>", lineNumber)
+		}
+
+		if !shell {
+			key = strings.TrimSpace(key)
+			rest = strings.TrimLeft(rest, " \t")
+		}
+
+		if !configKeyRe.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, key)
+		}
+
+		value, consumed, err := parseEnvValue(rest, lines[i+1:], shell)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		values[key] = value
+		i += consumed
+	}
+
+	return values, nil
+}
+
+// parseEnvValue parses the value portion of a line. Quoted values may continue
+// onto the following lines; the number of extra lines consumed is returned.
+func parseEnvValue(s string, following []string, shell bool) (string, int, error) {
+	if !shell && s != "" && s[0] != '\'' && s[0] != '"' {
+		value, _, _ := strings.Cut(s, " #")
+
+		return strings.TrimSpace(value), 0, nil
+	}
+
+	var b strings.Builder
+
+	consumed := 0
+	// nextLine extends s with the next line when a quoted value spans lines
+	nextLine := func(quote byte) error {
+		if consumed >= len(following) {
+			return fmt.Errorf("unterminated %c quote", quote)
+		}
+
+		s += "\n" + following[consumed]
+		consumed++
+
+		return nil
+	}
+
+	// shell words are concatenated until whitespace, dotenv values are a single quoted string
+	for s != "" && s[0] != ' ' && s[0] != '\t' {
+		switch s[0] {
+		case '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			for end < 0 {
+				if err := nextLine('\''); err != nil {
+					return "", 0, err
+				}
+
+				end = strings.IndexByte(s[1:], '\'')
+			}
+
+			b.WriteString(s[1 : end+1])
+			s = s[end+2:]
+		case '"':
+			i := 1
+
+			for {
+				if i >= len(s) {
+					if err := nextLine('"'); err != nil {
+						return "", 0, err
+					}
+
+					continue
+				}
+
+				if s[i] == '"' {
+					break
+				}
+
+				if s[i] == '\\' && i+1 < len(s) {
+					b.WriteString(unescapeDoubleQuoted(s[i+1], shell))
+					i += 2
+
+					continue
+				}
+
+				b.WriteByte(s[i])
+				i++
+			}
+
+			s = s[i+1:]
+		case '\\':
+			if len(s) > 1 {
+				b.WriteByte(s[1])
+			}
+
+			s = s[min(2, len(s)):]
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+
+		if !shell {
+			break
+		}
+	}
+
+	trailing := strings.TrimSpace(s)
+	if trailing != "" && !strings.HasPrefix(trailing, "#") {
+		return "", 0, fmt.Errorf("unexpected %q after value", trailing)
+	}
+
+	return b.String(), consumed, nil
+}
+
+// unescapeDoubleQuoted returns the result of a backslash escape inside double quotes
+func unescapeDoubleQuoted(c byte, shell bool) string {
+	switch c {
+	case '\\', '"', '$', '`':
+		return string(c)
+	}
+
+	if !shell {
+		switch c {
+		case 'n':
+			return "\n"
+		case 'r':
+			return "\r"
+		case 't':
+			return "\t"
+		}
+	}
+
+	return `\` + string(c)
+}
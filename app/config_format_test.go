@@ -0,0 +1,200 @@
+package app_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apppackio/apppack/app"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+func TestEncodeConfig(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{
+		"PLAIN":     "value",
+		"SPACES":    "hello world",
+		"QUOTES":    `it's "quoted"`,
+		"MULTILINE": "line1\nline2",
+		"DOLLAR":    "$HOME",
+	}
+
+	scenarios := map[string]string{
+		app.FormatDotenv: `DOLLAR="\$HOME"
+MULTILINE="line1\nline2"
+PLAIN=value
+QUOTES="it's \"quoted\""
+SPACES="hello world"`,
+		app.FormatShell: `export DOLLAR='$HOME'
+export MULTILINE='line1
+line2'
+export PLAIN=value
+export QUOTES='it'\''s "quoted"'
+export SPACES='hello world'`,
+		app.FormatYAML: `DOLLAR: $HOME
+MULTILINE: |-
+    line1
+    line2
+PLAIN: value
+QUOTES: it's "quoted"
+SPACES: hello world`,
+	}
+
+	for format, expected := range scenarios {
+		buf, err := app.EncodeConfig(values, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if actual := buf.String(); actual != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", format, expected, actual)
+		}
+	}
+
+	if _, err := app.EncodeConfig(values, "toml"); err == nil {
+		t.Error("expected error for unknown format, got nil")
+	}
+}
+
+func TestConfigFormatRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{
+		"PLAIN":     "value",
+		"EMPTY":     "",
+		"SPACES":    "  padded value  ",
+		"QUOTES":    `it's "quoted" \ back`,
+		"MULTILINE": "-----BEGIN KEY-----\nabc\n-----END KEY-----\n",
+		"HASH":      "abc #not-a-comment",
+	}
+
+	for _, format := range app.ConfigFormats {
+		buf, err := app.EncodeConfig(values, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		decoded, err := app.DecodeConfig(buf.Bytes(), format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(values, decoded) {
+			t.Errorf("%s: expected %v, got %v", format, values, decoded)
+		}
+	}
+}
+
+func TestConfigFormatPreservesType(t *testing.T) {
+	t.Parallel()
+
+	configVars := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "ENVIRONMENT", Value: "production", Type: ssmtypes.ParameterTypeString},
+		{Name: "SECRET_KEY", Value: "abc123", Type: ssmtypes.ParameterTypeSecureString},
+	})
+	expected := map[string]string{"ENVIRONMENT": "production", "SECRET_KEY": "abc123"}
+
+	for _, format := range app.ConfigFormats {
+		// exports without types only contain the variables
+		buf, err := configVars.ToFormat(format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		values, err := app.DecodeConfig(buf.Bytes(), format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: expected %v, got %v", format, expected, values)
+		}
+
+		buf, err = configVars.ToFormatWithTypes(format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		values, plainText, err := app.DecodeConfigWithTypes(buf.Bytes(), format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: expected %v, got %v", format, expected, values)
+		}
+
+		if !reflect.DeepEqual(plainText, []string{"ENVIRONMENT"}) {
+			t.Errorf("%s: expected [ENVIRONMENT], got %v", format, plainText)
+		}
+
+	}
+
+	// a plain export can't be imported with types
+	if _, _, err := app.DecodeConfigWithTypes([]byte(`{"SECRET_KEY": "abc123"}`), app.FormatJSON); err == nil {
+		t.Error("expected error for JSON without types, got nil")
+	}
+}
+
+func TestDecodeConfigDotenv(t *testing.T) {
+	t.Parallel()
+
+	data := `# comment
+export EXPORTED=1
+UNQUOTED=some value # trailing comment
+SINGLE='no $expansion\n'
+DOUBLE="tab\tnewline\n"
+SPANS="first
+second"
+`
+
+	expected := map[string]string{
+		"EXPORTED": "1",
+		"UNQUOTED": "some value",
+		"SINGLE":   `no $expansion\n`,
+		"DOUBLE":   "tab\tnewline\n",
+		"SPANS":    "first\nsecond",
+	}
+
+	actual, err := app.DecodeConfig([]byte(data), app.FormatDotenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestDecodeConfigErrorLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		format string
+		data   string
+		line   string
+	}{
+		{format: app.FormatDotenv, data: "FOO=bar\nnot a variable\n", line: "line 2:"},
+		{format: app.FormatDotenv, data: "FOO=bar\n\nBAD KEY=1\n", line: "line 3:"},
+		{format: app.FormatDotenv, data: "FOO=bar\nBAZ=\"unterminated\nQUX=1\n", line: "line 2:"},
+		{format: app.FormatDotenv, data: "FOO=\"bar\" extra\n", line: "line 1:"},
+		{format: app.FormatShell, data: "export FOO=bar\nexport BAZ='open\n", line: "line 2:"},
+		{format: app.FormatJSON, data: "{\n  \"FOO\": \"bar\",\n  \"BAZ\": 1\n}", line: "line 3:"},
+		{format: app.FormatJSON, data: "{\n  \"FOO\": \"bar\"\n  \"BAZ\": \"qux\"\n}", line: "line 3:"},
+		{format: app.FormatYAML, data: "FOO: bar\nBAZ:\n  nested: true\n", line: "line 3"},
+	}
+
+	for _, s := range scenarios {
+		_, err := app.DecodeConfig([]byte(s.data), s.format)
+		if err == nil {
+			t.Errorf("%s: expected error for %q, got nil", s.format, s.data)
+
+			continue
+		}
+
+		if !strings.Contains(err.Error(), s.line) {
+			t.Errorf("%s: expected error containing %q, got %q", s.format, s.line, err)
+		}
+	}
+}
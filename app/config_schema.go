@@ -0,0 +1,177 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigSchemaFile is the path of the config schema, relative to the current directory
+const DefaultConfigSchemaFile = "apppack.config.yml"
+
+const (
+	SchemaFormatURL     = "url"
+	SchemaFormatInteger = "integer"
+	SchemaFormatRegex   = "regex"
+)
+
+const (
+	ConfigProblemMissing = "missing"
+	ConfigProblemInvalid = "invalid"
+	ConfigProblemUnknown = "unknown"
+)
+
+// ConfigSchema describes the config variables an app expects, e.g.
+//
+//	variables:
+//	  SECRET_KEY:
+//	    description: Django secret key
+//	    format: regex
+//	    pattern: ".{50,}"
+//	  SENTRY_DSN:
+//	    format: url
+//	    optional: true
+type ConfigSchema struct {
+	Variables map[string]*ConfigSchemaVariable `yaml:"variables"`
+}
+
+// ConfigSchemaVariable describes a single variable in a ConfigSchema.
+// A regex Pattern must match the entire value.
+type ConfigSchemaVariable struct {
+	Description string `yaml:"description"`
+	Format      string `yaml:"format"`
+	Pattern     string `yaml:"pattern"`
+	Optional    bool   `yaml:"optional"`
+	patternRe   *regexp.Regexp
+}
+
+// ConfigProblem is a config variable which does not match the ConfigSchema
+type ConfigProblem struct {
+	Name        string
+	Problem     string
+	Message     string
+	Description string
+}
+
+// LoadConfigSchema reads and parses the ConfigSchema in filename
+func LoadConfigSchema(filename string) (*ConfigSchema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := ParseConfigSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config schema %s: %w", filename, err)
+	}
+
+	return schema, nil
+}
+
+// ParseConfigSchema parses a YAML (or JSON) ConfigSchema
+func ParseConfigSchema(data []byte) (*ConfigSchema, error) {
+	schema := ConfigSchema{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&schema); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for name, variable := range schema.Variables {
+		if err := ValidateConfigKey(name); err != nil {
+			return nil, err
+		}
+
+		if variable == nil {
+			variable = &ConfigSchemaVariable{}
+			schema.Variables[name] = variable
+		}
+
+		switch variable.Format {
+		case "", SchemaFormatURL, SchemaFormatInteger:
+			if variable.Pattern != "" {
+				return nil, fmt.Errorf("%s: pattern is only allowed with format %q", name, SchemaFormatRegex)
+			}
+		case SchemaFormatRegex:
+			re, err := regexp.Compile("^(?:" + variable.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			variable.patternRe = re
+		default:
+			return nil, fmt.Errorf("%s: unknown format %q -- must be one of %s, %s, %s", name, variable.Format, SchemaFormatURL, SchemaFormatInteger, SchemaFormatRegex)
+		}
+	}
+
+	return &schema, nil
+}
+
+// validate returns a message describing why the value doesn't match the variable format, or an empty string
+func (v *ConfigSchemaVariable) validate(value string) string {
+	switch v.Format {
+	case SchemaFormatURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "expected a URL"
+		}
+	case SchemaFormatInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "expected an integer"
+		}
+	case SchemaFormatRegex:
+		if !v.patternRe.MatchString(value) {
+			return fmt.Sprintf("expected a value matching %s", v.Pattern)
+		}
+	}
+
+	return ""
+}
+
+// Validate checks the config variables against the schema. Managed variables
+// can satisfy required variables, but are never reported as unknown.
+func (s *ConfigSchema) Validate(configVars ConfigVariables) []*ConfigProblem {
+	var problems []*ConfigProblem
+
+	values := map[string]*ConfigVariable{}
+	for _, configVar := range configVars {
+		values[configVar.Name] = configVar
+	}
+
+	for name, variable := range s.Variables {
+		configVar, ok := values[name]
+		if !ok {
+			if !variable.Optional {
+				problems = append(problems, &ConfigProblem{Name: name, Problem: ConfigProblemMissing, Message: "required variable is not set", Description: variable.Description})
+			}
+
+			continue
+		}
+
+		if message := variable.validate(configVar.Value); message != "" {
+			problems = append(problems, &ConfigProblem{Name: name, Problem: ConfigProblemInvalid, Message: message, Description: variable.Description})
+		}
+	}
+
+	for _, configVar := range configVars {
+		if _, ok := s.Variables[configVar.Name]; !ok && !configVar.Managed {
+			problems = append(problems, &ConfigProblem{Name: configVar.Name, Problem: ConfigProblemUnknown, Message: "variable is not in the schema"})
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Name < problems[j].Name
+	})
+
+	return problems
+}
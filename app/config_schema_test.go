@@ -0,0 +1,85 @@
+package app_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apppackio/apppack/app"
+)
+
+const testConfigSchema = `variables:
+  SECRET_KEY:
+    description: Django secret key
+    format: regex
+    pattern: "[a-z0-9]{8,}"
+  API_URL:
+    format: url
+  WORKERS:
+    format: integer
+  SENTRY_DSN:
+    format: url
+    optional: true
+  DATABASE_URL:
+`
+
+func TestConfigSchemaValidate(t *testing.T) {
+	t.Parallel()
+
+	schema, err := app.ParseConfigSchema([]byte(testConfigSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configVars := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "API_URL", Value: "not a url"},
+		{Name: "DATABASE_URL", Value: "postgres://db", Managed: true},
+		{Name: "EXTRA", Value: "1"},
+		{Name: "REDIS_URL", Value: "redis://cache", Managed: true},
+		{Name: "SECRET_KEY", Value: "abcdefgh!"},
+	})
+
+	expected := []*app.ConfigProblem{
+		{Name: "API_URL", Problem: app.ConfigProblemInvalid, Message: "expected a URL"},
+		{Name: "EXTRA", Problem: app.ConfigProblemUnknown, Message: "variable is not in the schema"},
+		{Name: "SECRET_KEY", Problem: app.ConfigProblemInvalid, Message: "expected a value matching [a-z0-9]{8,}", Description: "Django secret key"},
+		{Name: "WORKERS", Problem: app.ConfigProblemMissing, Message: "required variable is not set"},
+	}
+
+	problems := schema.Validate(configVars)
+	if !reflect.DeepEqual(problems, expected) {
+		for _, p := range problems {
+			t.Logf("%+v", p)
+		}
+
+		t.Errorf("expected %d problems, got %d", len(expected), len(problems))
+	}
+
+	valid := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "API_URL", Value: "https://api.example.com"},
+		{Name: "DATABASE_URL", Value: "postgres://db", Managed: true},
+		{Name: "SECRET_KEY", Value: "abcdefgh"},
+		{Name: "WORKERS", Value: "4"},
+	})
+
+	if problems := schema.Validate(valid); len(problems) != 0 {
+		t.Errorf("expected no problems, got %d", len(problems))
+	}
+}
+
+func TestParseConfigSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []string{
+		"variables:\n  FOO:\n    format: email\n",
+		"variables:\n  FOO:\n    format: regex\n    pattern: \"[\"\n",
+		"variables:\n  FOO:\n    format: url\n    pattern: \"http.*\"\n",
+		"variables:\n  FOO:\n    required: true\n",
+		"variables:\n  BAD KEY:\n    format: url\n",
+	}
+
+	for _, s := range scenarios {
+		if _, err := app.ParseConfigSchema([]byte(s)); err == nil {
+			t.Errorf("expected error for %q, got nil", s)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package app_test
 import (
 	"bytes"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/apppackio/apppack/app"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -80,6 +82,30 @@ func TestConfigVariablesToConsole(t *testing.T) {
 	}
 }
 
+func TestConfigVariablesToConsolePlainText(t *testing.T) {
+	t.Parallel()
+
+	c := app.NewConfigVariables([]ssmtypes.Parameter{
+		{Name: aws.String("/apppack/apps/myapp/config/FOO"), Value: aws.String("bar"), Type: ssmtypes.ParameterTypeString},
+		{Name: aws.String("/apppack/apps/myapp/config/BAZ"), Value: aws.String("qux"), Type: ssmtypes.ParameterTypeSecureString},
+	})
+	out := &bytes.Buffer{}
+	w := ansiterm.NewTabWriter(out, 8, 8, 0, '\t', 0)
+	c.ToConsole(w)
+
+	expected := "BAZ:\tqux\nFOO:\tbar\t(not encrypted)\n"
+
+	w.Flush()
+
+	if actual := out.String(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if plainText := c.PlainText(); len(plainText) != 1 || plainText[0] != "FOO" {
+		t.Errorf("expected [FOO], got %v", plainText)
+	}
+}
+
 func TestConfigVariablesTransform(t *testing.T) {
 	t.Parallel()
 
@@ -162,3 +188,456 @@ func TestConfigVariableLoadManaged(t *testing.T) {
 		t.Errorf("expected %s, got %s", errMock, err)
 	}
 }
+
+func TestConfigVariablesDiff(t *testing.T) {
+	t.Parallel()
+
+	c := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "CHANGED", Value: "old"},
+		{Name: "REMOVED", Value: "gone"},
+		{Name: "SAME", Value: "same"},
+	})
+	other := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "ADDED", Value: "new"},
+		{Name: "CHANGED", Value: "new"},
+		{Name: "SAME", Value: "same"},
+	})
+
+	expected := []app.ConfigDifference{
+		{Name: "ADDED", Change: app.ConfigAdded, NewValue: "new"},
+		{Name: "CHANGED", Change: app.ConfigChanged, OldValue: "old", NewValue: "new"},
+		{Name: "REMOVED", Change: app.ConfigRemoved, OldValue: "gone"},
+	}
+
+	diffs := c.Diff(other)
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d differences, got %d", len(expected), len(diffs))
+	}
+
+	for i, d := range diffs {
+		if *d != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], *d)
+		}
+	}
+
+	if diffs := c.Diff(c); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %d", len(diffs))
+	}
+}
+
+func TestConfigVariablesUnmanaged(t *testing.T) {
+	t.Parallel()
+
+	c := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "FOO", Value: "bar", Managed: false},
+		{Name: "BAZ", Value: "qux", Managed: true},
+	})
+
+	unmanaged := c.Unmanaged()
+	if len(unmanaged) != 1 || unmanaged[0].Name != "FOO" {
+		t.Errorf("expected only FOO, got %v", unmanaged)
+	}
+}
+
+func TestConfigVersionAsOf(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := app.NewConfigVersions([]ssmtypes.ParameterHistory{
+		{Version: 2, Value: aws.String("two"), LastModifiedDate: aws.Time(start.Add(2 * time.Hour))},
+		{Version: 1, Value: aws.String("one"), LastModifiedDate: aws.Time(start)},
+		{Version: 3, Value: aws.String("three"), LastModifiedDate: aws.Time(start.Add(4 * time.Hour))},
+	})
+
+	scenarios := []struct {
+		at       time.Time
+		expected int64
+	}{
+		{at: start, expected: 1},
+		{at: start.Add(3 * time.Hour), expected: 2},
+		{at: start.Add(4 * time.Hour), expected: 3},
+		{at: start.Add(24 * time.Hour), expected: 3},
+	}
+
+	for _, s := range scenarios {
+		v := app.ConfigVersionAsOf(history, s.at)
+		if v == nil {
+			t.Fatalf("expected version %d at %s, got nil", s.expected, s.at)
+		}
+
+		if v.Version != s.expected {
+			t.Errorf("expected version %d at %s, got %d", s.expected, s.at, v.Version)
+		}
+	}
+
+	if v := app.ConfigVersionAsOf(history, start.Add(-time.Hour)); v != nil {
+		t.Errorf("expected nil before the first version, got %d", v.Version)
+	}
+}
+
+func TestFindConfigVersion(t *testing.T) {
+	t.Parallel()
+
+	history := app.NewConfigVersions([]ssmtypes.ParameterHistory{
+		{Version: 1, Value: aws.String("one")},
+		{Version: 2, Value: aws.String("two")},
+	})
+
+	if v := app.FindConfigVersion(history, 2); v == nil || v.Value != "two" {
+		t.Errorf("expected version 2, got %+v", v)
+	}
+
+	if v := app.FindConfigVersion(history, 3); v != nil {
+		t.Errorf("expected nil for a missing version, got %+v", v)
+	}
+}
+
+func TestConfigVersionModifiedBy(t *testing.T) {
+	t.Parallel()
+
+	scenarios := map[string]string{
+		"arn:aws:sts::123456789012:assumed-role/AppPackRole/user@example.com": "user@example.com",
+		"arn:aws:iam::123456789012:user/admin":                                "admin",
+		"":                                                                    "",
+	}
+
+	for user, expected := range scenarios {
+		v := app.ConfigVersion{LastModifiedUser: user}
+		if actual := v.ModifiedBy(); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
+
+func TestSetConfigBatchRollback(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+	withDecryption := aws.Bool(true)
+	secure := ssmtypes.ParameterTypeSecureString
+
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/A"), WithDecryption: withDecryption,
+	}).Return(aws.String("old"), nil)
+	m.On("DescribeParameter", "/apppack/apps/test/config/A").Return(&ssmtypes.ParameterMetadata{
+		Type: ssmtypes.ParameterTypeString,
+	}, nil)
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/B"), WithDecryption: withDecryption,
+	}).Return(nil, &ssmtypes.ParameterNotFound{})
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/C"), WithDecryption: withDecryption,
+	}).Return(nil, &ssmtypes.ParameterNotFound{})
+
+	// writes happen in sorted order, C fails
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/A"), Type: secure, Overwrite: aws.Bool(true), Value: aws.String("1"),
+	}).Return(nil)
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/B"), Type: secure, Overwrite: aws.Bool(true), Value: aws.String("2"),
+	}).Return(nil)
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/C"), Type: secure, Overwrite: aws.Bool(true), Value: aws.String("3"),
+	}).Return(errMock)
+
+	// A is restored as plain text, B is removed
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/A"), Type: ssmtypes.ParameterTypeString, Overwrite: aws.Bool(true), Value: aws.String("old"),
+	}).Return(nil)
+	m.On("DeleteParameter", &ssm.DeleteParameterInput{
+		Name: aws.String("/apppack/apps/test/config/B"),
+	}).Return(nil)
+
+	err := a.SetConfigBatch(map[string]string{"A": "1", "B": "2", "C": "3"}, app.ConfigStorage{})
+	if !errors.Is(err, errMock) {
+		t.Errorf("expected %s, got %s", errMock, err)
+	}
+
+	m.AssertExpectations(t)
+}
+
+func TestSetConfigBatchInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+
+	err := a.SetConfigBatch(map[string]string{"GOOD": "1", "NOT VALID": "2"}, app.ConfigStorage{})
+	if err == nil {
+		t.Error("expected error for invalid key, got nil")
+	}
+
+	a.AWS.(*MockAWS).AssertNotCalled(t, "PutParameter")
+}
+
+func TestSetConfigBatchStorage(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/A"), WithDecryption: aws.Bool(true),
+	}).Return(nil, &ssmtypes.ParameterNotFound{})
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name:      aws.String("/apppack/apps/test/config/A"),
+		Type:      ssmtypes.ParameterTypeSecureString,
+		KeyId:     aws.String("alias/foo"),
+		Overwrite: aws.Bool(true),
+		Value:     aws.String("1"),
+	}).Return(nil)
+
+	if err := a.SetConfigBatch(map[string]string{"A": "1"}, app.ConfigStorage{KMSKeyID: "alias/foo"}); err != nil {
+		t.Error(err)
+	}
+
+	m.AssertExpectations(t)
+
+	err := a.SetConfigBatch(map[string]string{"A": "1"}, app.ConfigStorage{PlainText: true, KMSKeyID: "alias/foo"})
+	if err == nil {
+		t.Error("expected error for KMS key with plain text storage, got nil")
+	}
+}
+
+func TestSetConfigBatchKeepExisting(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/PLAIN"), WithDecryption: aws.Bool(true),
+	}).Return(aws.String("old"), nil)
+	m.On("DescribeParameter", "/apppack/apps/test/config/PLAIN").Return(&ssmtypes.ParameterMetadata{
+		Type: ssmtypes.ParameterTypeString,
+	}, nil)
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/CMK"), WithDecryption: aws.Bool(true),
+	}).Return(aws.String("old"), nil)
+	m.On("DescribeParameter", "/apppack/apps/test/config/CMK").Return(&ssmtypes.ParameterMetadata{
+		Type:  ssmtypes.ParameterTypeSecureString,
+		KeyId: aws.String("alias/foo"),
+	}, nil)
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/NEW"), WithDecryption: aws.Bool(true),
+	}).Return(nil, &ssmtypes.ParameterNotFound{})
+
+	// existing variables keep their type and key, new ones use the default
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/PLAIN"), Type: ssmtypes.ParameterTypeString, Overwrite: aws.Bool(true), Value: aws.String("1"),
+	}).Return(nil)
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/CMK"), Type: ssmtypes.ParameterTypeSecureString, KeyId: aws.String("alias/foo"), Overwrite: aws.Bool(true), Value: aws.String("2"),
+	}).Return(nil)
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/NEW"), Type: ssmtypes.ParameterTypeSecureString, Overwrite: aws.Bool(true), Value: aws.String("3"),
+	}).Return(nil)
+
+	err := a.SetConfigBatch(map[string]string{"PLAIN": "1", "CMK": "2", "NEW": "3"}, app.ConfigStorage{KeepExisting: true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	m.AssertExpectations(t)
+}
+
+func TestRestoreConfig(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/PLAIN"), WithDecryption: aws.Bool(true),
+	}).Return(aws.String("new"), nil)
+	m.On("DescribeParameter", "/apppack/apps/test/config/PLAIN").Return(&ssmtypes.ParameterMetadata{
+		Type: ssmtypes.ParameterTypeString,
+	}, nil)
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/CMK"), WithDecryption: aws.Bool(true),
+	}).Return(aws.String("new"), nil)
+	m.On("DescribeParameter", "/apppack/apps/test/config/CMK").Return(&ssmtypes.ParameterMetadata{
+		Type:  ssmtypes.ParameterTypeSecureString,
+		KeyId: aws.String("alias/foo"),
+	}, nil)
+
+	// restored values keep the type and key of the current parameter
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/PLAIN"), Type: ssmtypes.ParameterTypeString, Overwrite: aws.Bool(true), Value: aws.String("old"),
+	}).Return(nil)
+	m.On("PutParameter", &ssm.PutParameterInput{
+		Name: aws.String("/apppack/apps/test/config/CMK"), Type: ssmtypes.ParameterTypeSecureString, KeyId: aws.String("alias/foo"), Overwrite: aws.Bool(true), Value: aws.String("old"),
+	}).Return(nil)
+	m.On("DeleteParameter", &ssm.DeleteParameterInput{
+		Name: aws.String("/apppack/apps/test/config/GONE"),
+	}).Return(nil)
+
+	err := a.RestoreConfig(map[string]string{"PLAIN": "old", "CMK": "old"}, []string{"GONE"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	m.AssertExpectations(t)
+}
+
+func TestRestoreConfigFailedWrite(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+
+	m.On("GetParameter", &ssm.GetParameterInput{
+		Name: aws.String("/apppack/apps/test/config/A"), WithDecryption: aws.Bool(true),
+	}).Return(nil, errMock)
+
+	err := a.RestoreConfig(map[string]string{"A": "old"}, []string{"GONE"})
+	if !errors.Is(err, errMock) {
+		t.Errorf("expected %s, got %s", errMock, err)
+	}
+
+	m.AssertNotCalled(t, "DeleteParameter")
+}
+
+func TestRestoreConfigFailedRemoval(t *testing.T) {
+	t.Parallel()
+
+	a := &app.App{
+		Name: "test",
+		AWS:  &MockAWS{},
+	}
+	m := a.AWS.(*MockAWS)
+
+	m.On("DeleteParameter", &ssm.DeleteParameterInput{Name: aws.String("/apppack/apps/test/config/A")}).Return(errMock)
+	m.On("DeleteParameter", &ssm.DeleteParameterInput{Name: aws.String("/apppack/apps/test/config/B")}).Return(nil)
+	m.On("DeleteParameter", &ssm.DeleteParameterInput{Name: aws.String("/apppack/apps/test/config/C")}).Return(errMock)
+
+	// every removal is attempted and the error says what changed
+	err := a.RestoreConfig(nil, []string{"A", "B", "C"})
+	if !errors.Is(err, errMock) {
+		t.Fatalf("expected %s, got %s", errMock, err)
+	}
+
+	expected := "config partially restored (set: none; removed: B): removing A: mock error\nremoving C: mock error"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	m.AssertExpectations(t)
+}
+
+func TestNewConfigVersionsStorage(t *testing.T) {
+	t.Parallel()
+
+	history := app.NewConfigVersions([]ssmtypes.ParameterHistory{
+		{Version: 1, Type: ssmtypes.ParameterTypeSecureString, KeyId: aws.String("alias/aws/ssm")},
+		{Version: 2, Type: ssmtypes.ParameterTypeSecureString, KeyId: aws.String("alias/foo")},
+		{Version: 3, Type: ssmtypes.ParameterTypeString},
+	})
+
+	expected := []app.ConfigStorage{{}, {KMSKeyID: "alias/foo"}, {PlainText: true}}
+	for i, v := range history {
+		if v.Storage != expected[i] {
+			t.Errorf("version %d: expected %+v, got %+v", v.Version, expected[i], v.Storage)
+		}
+	}
+}
+
+func TestIsSensitiveConfigKey(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"DATABASE_URL", "AWS_SECRET", "GITHUB_TOKEN", "SECRET_KEY", "stripe_api_key", "SMTP_PASSWORD"} {
+		if !app.IsSensitiveConfigKey(name) {
+			t.Errorf("expected %s to be sensitive", name)
+		}
+	}
+
+	for _, name := range []string{"ENVIRONMENT", "DEBUG", "KEYBOARD_LAYOUT", "TOKEN_TTL", "REDIS_URL"} {
+		if app.IsSensitiveConfigKey(name) {
+			t.Errorf("expected %s not to be sensitive", name)
+		}
+	}
+}
+
+func TestConfigVariablesMasked(t *testing.T) {
+	t.Parallel()
+
+	c := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "DATABASE_URL", Value: "postgres://db"},
+		{Name: "DEBUG", Value: "false"},
+		{Name: "ENVIRONMENT", Value: "production"},
+	})
+
+	scenarios := []struct {
+		revealAll bool
+		reveal    []string
+		expected  []string
+	}{
+		{revealAll: false, reveal: nil, expected: []string{app.MaskedValue, app.MaskedValue, app.MaskedValue}},
+		{revealAll: true, reveal: nil, expected: []string{app.MaskedValue, "false", "production"}},
+		{revealAll: false, reveal: []string{"DATABASE_URL", "DEBUG"}, expected: []string{"postgres://db", "false", app.MaskedValue}},
+	}
+
+	for _, s := range scenarios {
+		masked := c.Masked(s.revealAll, s.reveal)
+		for i, v := range masked {
+			if v.Value != s.expected[i] {
+				t.Errorf("expected %s=%s, got %s", v.Name, s.expected[i], v.Value)
+			}
+		}
+	}
+
+	if c[0].Value != "postgres://db" {
+		t.Errorf("expected original value to be unchanged, got %s", c[0].Value)
+	}
+}
+
+func TestConfigVariablesFilter(t *testing.T) {
+	t.Parallel()
+
+	c := app.ConfigVariables([]*app.ConfigVariable{
+		{Name: "AWS_REGION"},
+		{Name: "AWS_SECRET"},
+		{Name: "DATABASE_URL"},
+		{Name: "DEBUG"},
+	})
+
+	scenarios := []struct {
+		only     []string
+		except   []string
+		expected []string
+	}{
+		{only: nil, except: nil, expected: []string{"AWS_REGION", "AWS_SECRET", "DATABASE_URL", "DEBUG"}},
+		{only: []string{"AWS_*", "DEBUG"}, except: nil, expected: []string{"AWS_REGION", "AWS_SECRET", "DEBUG"}},
+		{only: nil, except: []string{"*_SECRET", "DATABASE_URL"}, expected: []string{"AWS_REGION", "DEBUG"}},
+		{only: []string{"AWS_*"}, except: []string{"AWS_SECRET"}, expected: []string{"AWS_REGION"}},
+	}
+
+	for _, s := range scenarios {
+		var names []string
+		for _, v := range c.Filter(s.only, s.except) {
+			names = append(names, v.Name)
+		}
+
+		if !reflect.DeepEqual(names, s.expected) {
+			t.Errorf("only %v except %v: expected %v, got %v", s.only, s.except, s.expected, names)
+		}
+	}
+}
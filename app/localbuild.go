@@ -0,0 +1,280 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/codebuild"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// localSourcePrefix is the prefix of the S3 keys local source archives are uploaded to
+	localSourcePrefix = "apppack-local-source/"
+	// sourceDownloadTimeout is how long WaitForSourceDownload waits for CodeBuild to download the source
+	sourceDownloadTimeout = 30 * time.Minute
+)
+
+// LocalSourceRoot returns the top level directory of the git working tree containing dir
+func LocalSourceRoot(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("finding the git working tree (a local build must run in a git working tree): %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// LocalSourceFiles lists the files in the git working tree at dir which aren't ignored by .gitignore,
+// including untracked files. Deleted files which haven't been committed yet are left out.
+func LocalSourceFiles(dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing files with git (a local build must run in a git working tree): %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+
+	for name := range strings.SplitSeq(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		if name == "" {
+			continue
+		}
+
+		if _, err := os.Lstat(filepath.Join(dir, name)); errors.Is(err, os.ErrNotExist) {
+			logrus.WithFields(logrus.Fields{"file": name}).Debug("skipping deleted file")
+
+			continue
+		}
+
+		files = append(files, name)
+	}
+
+	return files, nil
+}
+
+// ZipLocalSource writes a zip archive of the files (relative to dir) to w.
+// Symlinks are stored as links rather than followed.
+func ZipLocalSource(dir string, files []string, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	for _, name := range files {
+		if err := addZipFile(archive, dir, name); err != nil {
+			return fmt.Errorf("adding %s: %w", name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+func addZipFile(archive *zip.Writer, dir, name string) error {
+	path := filepath.Join(dir, name)
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	// submodules are listed as directories
+	if info.IsDir() {
+		return nil
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = filepath.ToSlash(name)
+	header.Method = zip.Deflate
+
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(entry, filepath.ToSlash(target))
+
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(entry, f)
+
+	return err
+}
+
+// artifactBucket is the S3 bucket the app's CodeBuild project stores artifacts in
+func (a *App) artifactBucket() (string, error) {
+	project, err := a.codebuildProject()
+	if err != nil {
+		return "", err
+	}
+
+	artifacts := project.Artifacts
+	if artifacts == nil || artifacts.Type != codebuildetypes.ArtifactsTypeS3 || aws.ToString(artifacts.Location) == "" {
+		return "", fmt.Errorf("CodeBuild project %s doesn't store artifacts in S3", a.Settings.CodebuildProject.Name)
+	}
+
+	return aws.ToString(artifacts.Location), nil
+}
+
+// UploadLocalSource uploads the source archive to the app's S3 artifact location
+// and returns the location to use in BuildOptions.SourceLocation
+func (a *App) UploadLocalSource(archive io.Reader) (string, error) {
+	bucket, err := a.artifactBucket()
+	if err != nil {
+		return "", err
+	}
+
+	name := a.Name
+	if a.IsReviewApp() {
+		name = fmt.Sprintf("%s-pr%s", a.Name, *a.ReviewApp)
+	}
+
+	key := fmt.Sprintf("%s%s/%s.zip", localSourcePrefix, name, time.Now().UTC().Format("20060102T150405Z"))
+
+	logrus.WithFields(logrus.Fields{"bucket": bucket, "key": key}).Debug("uploading local source")
+
+	_, err = manager.NewUploader(s3.NewFromConfig(a.Session)).Upload(context.Background(), &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   archive,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return bucket + "/" + key, nil
+}
+
+// LocalSourceObject returns the S3 bucket and key of a source location returned by UploadLocalSource
+func LocalSourceObject(location string) (string, string, error) {
+	bucket, key, _ := strings.Cut(location, "/")
+	if bucket == "" || !strings.HasPrefix(key, localSourcePrefix) {
+		return "", "", fmt.Errorf("%s is not a local source archive", location)
+	}
+
+	return bucket, key, nil
+}
+
+// DeleteLocalSource deletes a source archive uploaded by UploadLocalSource
+func (a *App) DeleteLocalSource(location string) error {
+	bucket, key, err := LocalSourceObject(location)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"bucket": bucket, "key": key}).Debug("deleting local source")
+
+	_, err = s3.NewFromConfig(a.Session).DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+
+	return err
+}
+
+// SourceDownloaded reports whether CodeBuild no longer needs the build's source,
+// because the DOWNLOAD_SOURCE phase has ended or the build is complete
+func SourceDownloaded(build *codebuildetypes.Build) bool {
+	if build.BuildComplete {
+		return true
+	}
+
+	for _, phase := range build.Phases {
+		if phase.PhaseType == codebuildetypes.BuildPhaseTypeDownloadSource && phase.EndTime != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WaitForSourceDownload waits until CodeBuild has downloaded the source of the build with the ID
+func (a *App) WaitForSourceDownload(id string) error {
+	codebuildSvc := codebuild.NewFromConfig(a.Session)
+	deadline := time.Now().Add(sourceDownloadTimeout)
+
+	for {
+		resp, err := codebuildSvc.BatchGetBuilds(context.Background(), &codebuild.BatchGetBuildsInput{Ids: []string{id}})
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Builds) == 0 {
+			return fmt.Errorf("build %s not found in CodeBuild", id)
+		}
+
+		if SourceDownloaded(&resp.Builds[0]) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for build %s to download its source", id)
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// MarkLocalBuild records in the build's DynamoDB item that it was started from a local source archive,
+// so Local is read along with the rest of the build status
+func (a *App) MarkLocalBuild(buildNumber int) error {
+	key := fmt.Sprintf("BUILD#%010d", buildNumber)
+
+	logrus.WithFields(logrus.Fields{"build": buildNumber}).Debug("marking local build")
+
+	_, err := dynamodb.NewFromConfig(a.Session).UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName: aws.String("apppack"),
+		Key: map[string]dynamodbtypes.AttributeValue{
+			"primary_id":   &dynamodbtypes.AttributeValueMemberS{Value: a.ddbPrimaryID(key)},
+			"secondary_id": &dynamodbtypes.AttributeValueMemberS{Value: key},
+		},
+		ConditionExpression:       aws.String("attribute_exists(primary_id)"),
+		UpdateExpression:          aws.String("SET #local = :local"),
+		ExpressionAttributeNames:  map[string]string{"#local": "local"},
+		ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{":local": &dynamodbtypes.AttributeValueMemberBOOL{Value: true}},
+	})
+
+	return err
+}
@@ -0,0 +1,172 @@
+package app_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	codebuildetypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalSourceFiles(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s %s", err, out)
+	}
+
+	writeFiles(t, dir, map[string]string{
+		".gitignore":           "node_modules/\n*.log\n",
+		"app.py":               "print('hi')",
+		"debug.log":            "ignored",
+		"node_modules/x/a.js":  "ignored",
+		"static/css/style.css": "body {}",
+	})
+
+	files, err := app.LocalSourceFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{".gitignore", "app.py", "static/css/style.css"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+
+	if _, err := app.LocalSourceFiles(t.TempDir()); err == nil {
+		t.Error("expected error outside of a git working tree, got nil")
+	}
+
+	root, err := app.LocalSourceRoot(filepath.Join(dir, "static", "css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRoot, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actualRoot, _ := filepath.EvalSymlinks(root); actualRoot != expectedRoot {
+		t.Errorf("expected the working tree root %s from a subdirectory, got %s", expectedRoot, root)
+	}
+
+	if _, err := app.LocalSourceRoot(t.TempDir()); err == nil {
+		t.Error("expected error for the root outside of a git working tree, got nil")
+	}
+}
+
+func TestZipLocalSource(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"app.py": "print('hi')", "static/style.css": "body {}"})
+
+	if err := os.Symlink("app.py", filepath.Join(dir, "link.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := app.ZipLocalSource(dir, []string{"app.py", "static/style.css", "link.py"}, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := map[string]string{}
+
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents[f.Name] = string(data)
+
+		if f.Name == "link.py" && f.Mode()&os.ModeSymlink == 0 {
+			t.Error("expected link.py to be stored as a symlink")
+		}
+	}
+
+	expected := map[string]string{"app.py": "print('hi')", "static/style.css": "body {}", "link.py": "app.py"}
+	if !reflect.DeepEqual(contents, expected) {
+		t.Errorf("expected %v, got %v", expected, contents)
+	}
+}
+
+func TestLocalSourceObject(t *testing.T) {
+	t.Parallel()
+
+	bucket, key, err := app.LocalSourceObject("bucket/apppack-local-source/my-app/20260101T000000Z.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bucket != "bucket" || key != "apppack-local-source/my-app/20260101T000000Z.zip" {
+		t.Errorf("unexpected bucket %q and key %q", bucket, key)
+	}
+
+	for _, location := range []string{"bucket/other/source.zip", "/apppack-local-source/source.zip", "bucket"} {
+		if _, _, err := app.LocalSourceObject(location); err == nil {
+			t.Errorf("expected error for %s, got nil", location)
+		}
+	}
+}
+
+func TestSourceDownloaded(t *testing.T) {
+	t.Parallel()
+
+	downloading := &codebuildetypes.Build{Phases: []codebuildetypes.BuildPhase{
+		{PhaseType: codebuildetypes.BuildPhaseTypeSubmitted, EndTime: aws.Time(time.Now())},
+		{PhaseType: codebuildetypes.BuildPhaseTypeDownloadSource},
+	}}
+	if app.SourceDownloaded(downloading) {
+		t.Error("expected the source not to be downloaded while DOWNLOAD_SOURCE is running")
+	}
+
+	downloading.Phases[1].EndTime = aws.Time(time.Now())
+	if !app.SourceDownloaded(downloading) {
+		t.Error("expected the source to be downloaded once DOWNLOAD_SOURCE ended")
+	}
+
+	// a build which failed before downloading its source doesn't need it either
+	if !app.SourceDownloaded(&codebuildetypes.Build{BuildComplete: true}) {
+		t.Error("expected a complete build not to need its source")
+	}
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxRollbackRevisions is how many task definition revisions are searched for the one registered by a build
+	maxRollbackRevisions = 100
+	// maxEcsDescribeServiceCount is the most services DescribeServices accepts at once
+	maxEcsDescribeServiceCount = 10
+)
+
+// errNoBuildTaskDefinition is returned when the task definition registered by a build can't be found
+var errNoBuildTaskDefinition = errors.New("no task definition found")
+
+// PreviousSuccessfulBuild returns the newest build before the current build which deployed successfully.
+// Local builds are skipped because their source isn't in git. builds are ordered newest first, as returned by RecentBuilds.
+func PreviousSuccessfulBuild(builds []BuildStatus, current int) (*BuildStatus, error) {
+	for i := range builds {
+		if builds[i].BuildNumber < current && builds[i].Deploy.State == PhaseSuccess && !builds[i].Local {
+			return &builds[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no successful build found before build #%d", current)
+}
+
+// ServiceRollback is a service and the task definition it is rolled back to
+type ServiceRollback struct {
+	Process        string
+	Service        string
+	TaskDefinition string
+}
+
+// buildTaskDefinition finds the task definition revision in the family registered by the build
+func buildTaskDefinition(ecsSvc *ecs.Client, family string, buildNumber int, status ecstypes.TaskDefinitionStatus) (*ecstypes.TaskDefinition, error) {
+	paginator := ecs.NewListTaskDefinitionsPaginator(ecsSvc, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: &family,
+		Sort:         ecstypes.SortOrderDesc,
+		Status:       status,
+	})
+	searched := 0
+
+	for paginator.HasMorePages() && searched < maxRollbackRevisions {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, arn := range page.TaskDefinitionArns {
+			searched++
+
+			resp, err := ecsSvc.DescribeTaskDefinition(context.Background(), &ecs.DescribeTaskDefinitionInput{
+				TaskDefinition: aws.String(arn),
+				Include:        []ecstypes.TaskDefinitionField{ecstypes.TaskDefinitionFieldTags},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// FamilyPrefix also matches longer family names, e.g. my-app-web and my-app-webhooks
+			if aws.ToString(resp.TaskDefinition.Family) != family {
+				continue
+			}
+
+			tag, err := tagValue(resp.Tags, "apppack:buildNumber")
+			if err != nil {
+				continue
+			}
+
+			revisionBuild, err := strconv.Atoi(tag)
+			if err != nil {
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{"task_definition": arn, "build": revisionBuild}).Debug("checking task definition")
+
+			if revisionBuild == buildNumber {
+				return resp.TaskDefinition, nil
+			}
+			// revisions are newest first, so older builds won't be found after this
+			if revisionBuild < buildNumber {
+				return nil, fmt.Errorf("%w for build #%d in %s", errNoBuildTaskDefinition, buildNumber, family)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w for build #%d in the last %d revisions of %s", errNoBuildTaskDefinition, buildNumber, maxRollbackRevisions, family)
+}
+
+// tagValue returns the value of the tag with the key
+func tagValue(tags []ecstypes.Tag, key string) (string, error) {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value), nil
+		}
+	}
+
+	return "", fmt.Errorf("tag %s not found", key)
+}
+
+// DeployedBuildNumber returns the build the app's services are running, read from the
+// apppack:buildNumber tag of the task definition each service is deploying. Unlike the deploy
+// status, it reflects rollbacks. If the services run different builds, e.g. after a partial
+// rollback, the newest is returned.
+func (a *App) DeployedBuildNumber() (int, error) {
+	if err := a.LoadSettings(); err != nil {
+		return 0, err
+	}
+
+	if err := a.LoadDeployStatus(); err != nil {
+		return 0, err
+	}
+
+	if a.DeployStatus == nil || len(a.DeployStatus.Processes) == 0 {
+		return 0, errors.New("the app has no services")
+	}
+
+	services := make([]string, 0, len(a.DeployStatus.Processes))
+	for _, process := range a.DeployStatus.Processes {
+		services = append(services, a.ServiceName(process.Name))
+	}
+
+	described, err := a.DescribeServices(services)
+	if err != nil {
+		return 0, err
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+	deployed := 0
+
+	for i := range described {
+		buildNumber, err := serviceBuildNumber(ecsSvc, &described[i])
+		if err != nil {
+			return 0, err
+		}
+
+		logrus.WithFields(logrus.Fields{"service": aws.ToString(described[i].ServiceName), "build": buildNumber}).Debug("found deployed build")
+
+		deployed = max(deployed, buildNumber)
+	}
+
+	return deployed, nil
+}
+
+// DescribeServices describes the ECS services in the app's cluster, in batches DescribeServices accepts
+func (a *App) DescribeServices(services []string) ([]ecstypes.Service, error) {
+	if err := a.LoadSettings(); err != nil {
+		return nil, err
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+
+	var described []ecstypes.Service
+
+	for chunk := range slices.Chunk(services, maxEcsDescribeServiceCount) {
+		resp, err := ecsSvc.DescribeServices(context.Background(), &ecs.DescribeServicesInput{
+			Cluster:  &a.Settings.Cluster.ARN,
+			Services: chunk,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		described = append(described, resp.Services...)
+	}
+
+	return described, nil
+}
+
+// serviceBuildNumber returns the build of the task definition the service's primary deployment is using
+func serviceBuildNumber(ecsSvc *ecs.Client, service *ecstypes.Service) (int, error) {
+	taskDefinition := service.TaskDefinition
+
+	for _, d := range service.Deployments {
+		if aws.ToString(d.Status) == "PRIMARY" {
+			taskDefinition = d.TaskDefinition
+		}
+	}
+
+	resp, err := ecsSvc.DescribeTaskDefinition(context.Background(), &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: taskDefinition,
+		Include:        []ecstypes.TaskDefinitionField{ecstypes.TaskDefinitionFieldTags},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tagValue(resp.Tags, "apppack:buildNumber")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", aws.ToString(taskDefinition), err)
+	}
+
+	return strconv.Atoi(tag)
+}
+
+// PlanRollback finds the task definition the build registered for each of the app's services
+func (a *App) PlanRollback(build *BuildStatus) ([]ServiceRollback, error) {
+	if err := a.LoadSettings(); err != nil {
+		return nil, err
+	}
+
+	if err := a.LoadDeployStatus(); err != nil {
+		return nil, err
+	}
+
+	if a.DeployStatus == nil || len(a.DeployStatus.Processes) == 0 {
+		return nil, errors.New("the app has no services to roll back")
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+	plan := make([]ServiceRollback, 0, len(a.DeployStatus.Processes))
+
+	for _, process := range a.DeployStatus.Processes {
+		service := a.ServiceName(process.Name)
+
+		// inactive task definitions can't be deployed
+		taskDefinition, err := buildTaskDefinition(ecsSvc, service, build.BuildNumber, ecstypes.TaskDefinitionStatusActive)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = append(plan, ServiceRollback{Process: process.Name, Service: service, TaskDefinition: aws.ToString(taskDefinition.TaskDefinitionArn)})
+	}
+
+	return plan, nil
+}
+
+// Rollback updates each service to use the task definition in the plan
+func (a *App) Rollback(plan []ServiceRollback) error {
+	if err := a.LoadSettings(); err != nil {
+		return err
+	}
+
+	ecsSvc := ecs.NewFromConfig(a.Session)
+
+	for _, s := range plan {
+		logrus.WithFields(logrus.Fields{"service": s.Service, "task_definition": s.TaskDefinition}).Debug("rolling back service")
+
+		_, err := ecsSvc.UpdateService(context.Background(), &ecs.UpdateServiceInput{
+			Cluster:        &a.Settings.Cluster.ARN,
+			Service:        aws.String(s.Service),
+			TaskDefinition: aws.String(s.TaskDefinition),
+		})
+		if err != nil {
+			return fmt.Errorf("updating service %s: %w", s.Service, err)
+		}
+	}
+
+	return nil
+}
+
+// RolloutComplete reports whether the service has finished deploying the task definition.
+// It returns an error if the deployment failed.
+func RolloutComplete(service *ecstypes.Service, taskDefinition string) (bool, error) {
+	for _, d := range service.Deployments {
+		if aws.ToString(d.Status) != "PRIMARY" {
+			continue
+		}
+
+		if aws.ToString(d.TaskDefinition) != taskDefinition {
+			return false, fmt.Errorf("%s is deploying a different task definition %s", aws.ToString(service.ServiceName), aws.ToString(d.TaskDefinition))
+		}
+
+		switch d.RolloutState {
+		case ecstypes.DeploymentRolloutStateFailed:
+			return false, fmt.Errorf("deploying %s failed: %s", aws.ToString(service.ServiceName), strings.TrimSpace(aws.ToString(d.RolloutStateReason)))
+		case ecstypes.DeploymentRolloutStateCompleted:
+			return true, nil
+		case ecstypes.DeploymentRolloutStateInProgress:
+			return false, nil
+		}
+
+		// services without a rollout state are done when the old deployments are drained
+		return len(service.Deployments) == 1 && d.RunningCount == d.DesiredCount, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,82 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestPreviousSuccessfulBuild(t *testing.T) {
+	t.Parallel()
+
+	builds := []app.BuildStatus{
+		{BuildNumber: 5, Deploy: app.BuildPhaseDetail{State: app.PhaseSuccess}},
+		{BuildNumber: 4, Deploy: app.BuildPhaseDetail{State: app.PhaseSuccess}},
+		{BuildNumber: 3, Deploy: app.BuildPhaseDetail{State: app.PhaseFailed}},
+		{BuildNumber: 2, Deploy: app.BuildPhaseDetail{State: app.PhaseSuccess}},
+	}
+
+	build, err := app.PreviousSuccessfulBuild(builds, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if build.BuildNumber != 2 {
+		t.Errorf("expected build 2, got %d", build.BuildNumber)
+	}
+
+	if _, err := app.PreviousSuccessfulBuild(builds, 2); err == nil {
+		t.Error("expected error when there is no earlier successful build, got nil")
+	}
+
+	builds[3].Local = true
+	if _, err := app.PreviousSuccessfulBuild(builds, 4); err == nil {
+		t.Error("expected local builds to be skipped, got nil")
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	t.Parallel()
+
+	service := func(deployments ...ecstypes.Deployment) *ecstypes.Service {
+		return &ecstypes.Service{ServiceName: aws.String("my-app-web"), Deployments: deployments}
+	}
+	primary := func(taskDefinition string, state ecstypes.DeploymentRolloutState) ecstypes.Deployment {
+		return ecstypes.Deployment{
+			Status:         aws.String("PRIMARY"),
+			TaskDefinition: aws.String(taskDefinition),
+			RolloutState:   state,
+			DesiredCount:   2,
+			RunningCount:   2,
+		}
+	}
+	active := ecstypes.Deployment{Status: aws.String("ACTIVE"), TaskDefinition: aws.String("web:2")}
+
+	tests := []struct {
+		name     string
+		service  *ecstypes.Service
+		complete bool
+		err      bool
+	}{
+		{"completed", service(primary("web:1", ecstypes.DeploymentRolloutStateCompleted)), true, false},
+		{"in progress", service(primary("web:1", ecstypes.DeploymentRolloutStateInProgress), active), false, false},
+		{"failed", service(primary("web:1", ecstypes.DeploymentRolloutStateFailed)), false, true},
+		{"different task definition", service(primary("web:3", ecstypes.DeploymentRolloutStateInProgress)), false, true},
+		{"no rollout state, draining", service(primary("web:1", ""), active), false, false},
+		{"no rollout state, drained", service(primary("web:1", "")), true, false},
+		{"no primary deployment", service(active), false, false},
+	}
+
+	for _, tt := range tests {
+		complete, err := app.RolloutComplete(tt.service, "web:1")
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.err, err)
+		}
+
+		if complete != tt.complete {
+			t.Errorf("%s: expected complete %t, got %t", tt.name, tt.complete, complete)
+		}
+	}
+}
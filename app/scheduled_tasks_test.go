@@ -25,12 +25,36 @@ func (m *MockAWS) GetParameter(input *ssm.GetParameterInput) (*string, error) {
 	return args.Get(0).(*string), args.Error(1)
 }
 
+func (m *MockAWS) DescribeParameter(name string) (*ssmtypes.ParameterMetadata, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*ssmtypes.ParameterMetadata), args.Error(1)
+}
+
 func (m *MockAWS) PutParameter(input *ssm.PutParameterInput) error {
 	args := m.Called(input)
 
 	return args.Error(0)
 }
 
+func (m *MockAWS) DeleteParameter(input *ssm.DeleteParameterInput) error {
+	args := m.Called(input)
+
+	return args.Error(0)
+}
+
+func (m *MockAWS) GetParameterHistory(input *ssm.GetParameterHistoryInput) ([]ssmtypes.ParameterHistory, error) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]ssmtypes.ParameterHistory), args.Error(1)
+}
+
 func (m *MockAWS) ValidateEventbridgeCron(rule string) error {
 	args := m.Called(rule)
 
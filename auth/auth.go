@@ -10,7 +10,6 @@ import (
 	"github.com/apppackio/apppack/state"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/sirupsen/logrus"
 )
 
@@ -72,19 +71,17 @@ func AppAWSSession(appName string, sessionDuration int) (aws.Config, *AppRole, e
 		return aws.Config{}, nil, err
 	}
 
-	creds, err := tokens.GetCredentials(appRole, sessionDuration)
+	provider := NewRefreshingCredentialsProvider(appRole, sessionDuration)
+	// assume the role now so errors are reported before the config is used
+	creds, err := provider.Retrieve(context.Background())
 	if err != nil {
 		return aws.Config{}, nil, err
 	}
 
-	logrus.WithFields(logrus.Fields{"access key": *creds.AccessKeyId}).Debug("creating AWS config")
+	logrus.WithFields(logrus.Fields{"access key": creds.AccessKeyID}).Debug("creating AWS config")
 
 	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			*creds.AccessKeyId,
-			*creds.SecretAccessKey,
-			*creds.SessionToken,
-		)),
+		config.WithCredentialsProvider(newCredentialsCache(provider)),
 		config.WithRegion(appRole.Region),
 		ignoreSharedConfigFiles(),
 	)
@@ -106,23 +103,21 @@ func AdminAWSSession(idOrAlias string, sessionDuration int, region string) (aws.
 		return aws.Config{}, nil, err
 	}
 
-	creds, err := tokens.GetCredentials(adminRole, sessionDuration)
+	provider := NewRefreshingCredentialsProvider(adminRole, sessionDuration)
+	// assume the role now so errors are reported before the config is used
+	creds, err := provider.Retrieve(context.Background())
 	if err != nil {
 		return aws.Config{}, nil, err
 	}
 
-	logrus.WithFields(logrus.Fields{"access_key": *creds.AccessKeyId}).Debug("creating AWS config")
+	logrus.WithFields(logrus.Fields{"access_key": creds.AccessKeyID}).Debug("creating AWS config")
 
 	if region == "" {
 		region = adminRole.Region
 	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			*creds.AccessKeyId,
-			*creds.SecretAccessKey,
-			*creds.SessionToken,
-		)),
+		config.WithCredentialsProvider(newCredentialsCache(provider)),
 		config.WithRegion(region),
 		ignoreSharedConfigFiles(),
 	)
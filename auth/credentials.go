@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/sirupsen/logrus"
+)
+
+// credentialsRefreshWindow is how long before they expire credentials are replaced
+const credentialsRefreshWindow = 5 * time.Minute
+
+// RefreshingCredentialsProvider assumes a role with the cached OAuth tokens and assumes it
+// again shortly before the credentials expire, so long-running commands like `logs -f`
+// can run for longer than the role's session duration.
+type RefreshingCredentialsProvider struct {
+	assume  func() (*types.Credentials, error)
+	current aws.Credentials
+}
+
+// NewRefreshingCredentialsProvider creates a provider for the role
+func NewRefreshingCredentialsProvider(role Role, sessionDuration int) *RefreshingCredentialsProvider {
+	return &RefreshingCredentialsProvider{
+		assume: func() (*types.Credentials, error) {
+			// the OAuth tokens are refreshed if they expired since the last time the role was assumed
+			tokens, err := GetTokens()
+			if err != nil {
+				return nil, err
+			}
+
+			return tokens.GetCredentials(role, sessionDuration)
+		},
+	}
+}
+
+// Retrieve returns the current credentials, assuming the role again if they are about to expire.
+// If the role can't be assumed, the current credentials are used until they expire.
+func (p *RefreshingCredentialsProvider) Retrieve(_ context.Context) (aws.Credentials, error) {
+	if p.current.HasKeys() && (!p.current.CanExpire || time.Until(p.current.Expires) > credentialsRefreshWindow) {
+		return p.current, nil
+	}
+
+	creds, err := p.assume()
+	if err != nil {
+		if p.current.HasKeys() && !p.current.Expired() {
+			logrus.WithFields(logrus.Fields{"error": err, "expires": p.current.Expires}).Debug("unable to refresh credentials")
+
+			return p.current, nil
+		}
+
+		return aws.Credentials{}, err
+	}
+
+	if creds == nil || creds.AccessKeyId == nil || creds.SecretAccessKey == nil || creds.SessionToken == nil {
+		return aws.Credentials{}, errors.New("assume role returned incomplete credentials")
+	}
+
+	logrus.WithFields(logrus.Fields{"access_key": *creds.AccessKeyId, "expires": creds.Expiration}).Debug("assumed role")
+
+	p.current = aws.Credentials{
+		AccessKeyID:     *creds.AccessKeyId,
+		SecretAccessKey: *creds.SecretAccessKey,
+		SessionToken:    *creds.SessionToken,
+		Source:          "AppPack",
+		CanExpire:       creds.Expiration != nil,
+		Expires:         aws.ToTime(creds.Expiration),
+	}
+
+	return p.current, nil
+}
+
+// newCredentialsCache wraps the provider in a cache which retrieves new credentials
+// at the same time the provider refreshes them
+func newCredentialsCache(provider aws.CredentialsProvider) *aws.CredentialsCache {
+	return aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = credentialsRefreshWindow
+	})
+}
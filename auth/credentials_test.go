@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// fakeAssume returns credentials expiring after each of the lifetimes in turn, or err if it is set
+type fakeAssume struct {
+	calls     int
+	lifetimes []time.Duration
+	err       error
+}
+
+func (f *fakeAssume) assume() (*types.Credentials, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	lifetime := f.lifetimes[min(f.calls, len(f.lifetimes))-1]
+
+	return &types.Credentials{
+		AccessKeyId:     aws.String(fmt.Sprintf("AKIA%d", f.calls)),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(lifetime)),
+	}, nil
+}
+
+func TestRefreshingCredentialsProvider(t *testing.T) {
+	t.Run("credentials are reused until they are about to expire", func(t *testing.T) {
+		fake := &fakeAssume{lifetimes: []time.Duration{time.Hour}}
+		provider := &RefreshingCredentialsProvider{assume: fake.assume}
+
+		for range 3 {
+			creds, err := provider.Retrieve(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.AccessKeyID != "AKIA1" || !creds.CanExpire {
+				t.Errorf("expected expiring AKIA1 credentials, got %+v", creds)
+			}
+		}
+		if fake.calls != 1 {
+			t.Errorf("expected the role to be assumed once, got %d", fake.calls)
+		}
+	})
+
+	t.Run("role is assumed again shortly before expiry", func(t *testing.T) {
+		fake := &fakeAssume{lifetimes: []time.Duration{2 * time.Minute, time.Hour}}
+		provider := &RefreshingCredentialsProvider{assume: fake.assume}
+
+		if _, err := provider.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		creds, err := provider.Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.AccessKeyID != "AKIA2" {
+			t.Errorf("expected refreshed AKIA2 credentials, got %s", creds.AccessKeyID)
+		}
+	})
+
+	t.Run("current credentials are used if refreshing fails", func(t *testing.T) {
+		fake := &fakeAssume{lifetimes: []time.Duration{2 * time.Minute}}
+		provider := &RefreshingCredentialsProvider{assume: fake.assume}
+
+		if _, err := provider.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		fake.err = errors.New("network issue")
+		creds, err := provider.Retrieve(context.Background())
+		if err != nil {
+			t.Fatalf("expected current credentials, got %v", err)
+		}
+		if creds.AccessKeyID != "AKIA1" {
+			t.Errorf("expected AKIA1 credentials, got %s", creds.AccessKeyID)
+		}
+	})
+
+	t.Run("error is returned if refreshing fails after expiry", func(t *testing.T) {
+		fake := &fakeAssume{lifetimes: []time.Duration{-time.Minute}}
+		provider := &RefreshingCredentialsProvider{assume: fake.assume}
+
+		if _, err := provider.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		fake.err = errors.New("network issue")
+		if _, err := provider.Retrieve(context.Background()); !errors.Is(err, fake.err) {
+			t.Errorf("expected %v, got %v", fake.err, err)
+		}
+	})
+
+	t.Run("cache retrieves new credentials before expiry", func(t *testing.T) {
+		fake := &fakeAssume{lifetimes: []time.Duration{2 * time.Minute, time.Hour}}
+		cache := newCredentialsCache(&RefreshingCredentialsProvider{assume: fake.assume})
+
+		first, err := cache.Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := cache.Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.AccessKeyID != "AKIA1" || second.AccessKeyID != "AKIA2" {
+			t.Errorf("expected AKIA1 then AKIA2, got %s then %s", first.AccessKeyID, second.AccessKeyID)
+		}
+	})
+}
@@ -5,11 +5,15 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 )
 
 type Interface interface {
 	GetParameter(input *ssm.GetParameterInput) (*string, error)
+	DescribeParameter(name string) (*ssmtypes.ParameterMetadata, error)
 	PutParameter(input *ssm.PutParameterInput) error
+	DeleteParameter(input *ssm.DeleteParameterInput) error
+	GetParameterHistory(input *ssm.GetParameterHistoryInput) ([]ssmtypes.ParameterHistory, error)
 	ValidateEventbridgeCron(schedule string) error
 }
 
@@ -34,9 +38,56 @@ func (a *AWS) GetParameter(input *ssm.GetParameterInput) (*string, error) {
 	return parameterOutput.Parameter.Value, nil
 }
 
+// DescribeParameter returns the metadata (type, KMS key, etc.) of a parameter.
+// It returns a ParameterNotFound error if the parameter doesn't exist.
+func (a *AWS) DescribeParameter(name string) (*ssmtypes.ParameterMetadata, error) {
+	ssmSvc := ssm.NewFromConfig(a.cfg)
+
+	resp, err := ssmSvc.DescribeParameters(context.Background(), &ssm.DescribeParametersInput{
+		ParameterFilters: []ssmtypes.ParameterStringFilter{
+			{Key: aws.String("Name"), Option: aws.String("Equals"), Values: []string{name}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Parameters) == 0 {
+		return nil, &ssmtypes.ParameterNotFound{Message: aws.String(name)}
+	}
+
+	return &resp.Parameters[0], nil
+}
+
 func (a *AWS) PutParameter(input *ssm.PutParameterInput) error {
 	ssmSvc := ssm.NewFromConfig(a.cfg)
 	_, err := ssmSvc.PutParameter(context.Background(), input)
 
 	return err
 }
+
+func (a *AWS) DeleteParameter(input *ssm.DeleteParameterInput) error {
+	ssmSvc := ssm.NewFromConfig(a.cfg)
+	_, err := ssmSvc.DeleteParameter(context.Background(), input)
+
+	return err
+}
+
+// GetParameterHistory returns every page of the parameter's history, oldest first
+func (a *AWS) GetParameterHistory(input *ssm.GetParameterHistoryInput) ([]ssmtypes.ParameterHistory, error) {
+	ssmSvc := ssm.NewFromConfig(a.cfg)
+
+	var history []ssmtypes.ParameterHistory
+
+	paginator := ssm.NewGetParameterHistoryPaginator(ssmSvc, input)
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, resp.Parameters...)
+	}
+
+	return history, nil
+}
@@ -58,29 +58,16 @@ import (
 // SOFTWARE.
 
 func execEnvironment(command string, args []string, cfg aws.Config) error {
-	creds, err := cfg.Credentials.Retrieve(context.Background())
-	if err != nil {
-		// return fmt.Errorf("Failed to get credentials for %s: %w", input.ProfileName, err)
-		return err
-	}
-
 	env := environ(os.Environ())
-
-	// log.Println("Setting subprocess env: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY")
-	env.Set("AWS_ACCESS_KEY_ID", creds.AccessKeyID)
-	env.Set("AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey)
-
-	if creds.SessionToken != "" {
-		// log.Println("Setting subprocess env: AWS_SESSION_TOKEN, AWS_SECURITY_TOKEN")
-		env.Set("AWS_SESSION_TOKEN", creds.SessionToken)
-		env.Set("AWS_SECURITY_TOKEN", creds.SessionToken)
+	if err := env.SetAWSCredentials(cfg); err != nil {
+		return err
 	}
 
-	if !creds.Expires.IsZero() {
-		// log.Println("Setting subprocess env: AWS_SESSION_EXPIRATION")
-		env.Set("AWS_SESSION_EXPIRATION", creds.Expires.UTC().Format(time.RFC3339))
-	}
+	return execWithEnviron(command, args, env)
+}
 
+// execWithEnviron replaces the current process with the command if possible, otherwise runs it as a subprocess
+func execWithEnviron(command string, args []string, env environ) error {
 	if !supportsExecSyscall() {
 		return execCmd(command, args, env)
 	}
@@ -109,6 +96,32 @@ func (e *environ) Set(key, val string) {
 	*e = append(*e, key+"="+val)
 }
 
+// SetAWSCredentials adds the credentials from the AWS config to the environment
+func (e *environ) SetAWSCredentials(cfg aws.Config) error {
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		// return fmt.Errorf("Failed to get credentials for %s: %w", input.ProfileName, err)
+		return err
+	}
+
+	// log.Println("Setting subprocess env: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY")
+	e.Set("AWS_ACCESS_KEY_ID", creds.AccessKeyID)
+	e.Set("AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey)
+
+	if creds.SessionToken != "" {
+		// log.Println("Setting subprocess env: AWS_SESSION_TOKEN, AWS_SECURITY_TOKEN")
+		e.Set("AWS_SESSION_TOKEN", creds.SessionToken)
+		e.Set("AWS_SECURITY_TOKEN", creds.SessionToken)
+	}
+
+	if !creds.Expires.IsZero() {
+		// log.Println("Setting subprocess env: AWS_SESSION_EXPIRATION")
+		e.Set("AWS_SESSION_EXPIRATION", creds.Expires.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
 func execCmd(command string, args, env []string) error {
 	cmd := osexec.Command(command, args...)
 	cmd.Stdin = os.Stdin
@@ -19,6 +19,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -29,7 +32,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/codebuild"
+	codebuildtypes "github.com/aws/aws-sdk-go-v2/service/codebuild/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/dustin/go-humanize"
 	"github.com/logrusorgru/aurora"
 	"github.com/sirupsen/logrus"
@@ -56,6 +61,7 @@ type buildStatusJSON struct {
 	Release     app.BuildPhaseDetail `json:"release"`
 	Postdeploy  app.BuildPhaseDetail `json:"postdeploy"`
 	Deploy      app.BuildPhaseDetail `json:"deploy"`
+	Local       bool                 `json:"local"`
 }
 
 func toBuildStatusJSON(b *app.BuildStatus) buildStatusJSON {
@@ -70,6 +76,7 @@ func toBuildStatusJSON(b *app.BuildStatus) buildStatusJSON {
 		Release:     b.Release,
 		Postdeploy:  b.Postdeploy,
 		Deploy:      b.Deploy,
+		Local:       b.Local,
 	}
 }
 
@@ -110,6 +117,11 @@ func printBuild(buildStatus *app.BuildStatus) {
 	}
 
 	fmt.Print(" ", aurora.Blue(buildStatus.Commit))
+
+	if buildStatus.Local {
+		fmt.Print(" ", aurora.Magenta("[local]"))
+	}
+
 	fmt.Printf("\n%s", indentStr)
 
 	finalPhase, _ := buildStatus.FinalPhase()
@@ -165,7 +177,15 @@ func pollBuildStatus(a *app.App, buildNumber, retries int) (*app.BuildStatus, er
 	return buildStatus, nil
 }
 
+// watchBuild shows the progress of the build until it is deployed, then notifies the user
 func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
+	err := followBuild(a, buildStatus, &buildOutput{})
+	notifyBuildFinished(a, buildStatus, err)
+
+	return err
+}
+
+func followBuild(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	var lastPhase *app.BuildPhase
 
 	var currentPhase *app.BuildPhase
@@ -184,6 +204,13 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 	}
 
 	for {
+		if out.stopped.Load() {
+			return errBuildWatchTimeout
+		}
+
+		if out.events != nil {
+			out.events.phases(buildStatus)
+		}
 		// catch up with any already completed phases
 		if lastPhase == nil {
 			logrus.Debug("setting current phase to Build")
@@ -193,7 +220,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 		} else {
 			failedPhase = buildStatus.FirstFailedPhase()
 			if failedPhase != nil {
-				return fmt.Errorf("%s failed at %s", failedPhase.Name, failedPhase.Phase.EndTime().Local().Format(timeFmt))
+				return &phaseFailedError{phase: failedPhase}
 			}
 
 			currentPhase = buildStatus.NextActivePhase(lastPhase)
@@ -212,7 +239,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 			}
 
 			if finalPhase.Phase.State == "failed" {
-				return fmt.Errorf("%s failed at %s", finalPhase.Name, finalPhase.Phase.EndTime().Local().Format(timeFmt))
+				return &phaseFailedError{phase: finalPhase}
 			}
 
 			if finalPhase.Name == "Deploy" {
@@ -239,14 +266,16 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 				time.Sleep(1 * time.Second)
 			}
 
-			fmt.Printf("\n⚡️ %s\t%s\n", aurora.Yellow(status), aurora.Faint(currentPhase.Phase.StartTime().Local().Format(timeFmt)))
+			if out.text() {
+				fmt.Printf("\n⚡️ %s\t%s\n", aurora.Yellow(status), aurora.Faint(currentPhase.Phase.StartTime().Local().Format(timeFmt)))
+			}
 			ui.StartSpinner()
 
 			lastPhase = currentPhase
 
 			switch currentPhase.Name {
 			case "Build":
-				err = watchBuildPhase(a, buildStatus)
+				err = watchBuildPhase(a, buildStatus, out)
 				if err != nil {
 					return err
 				}
@@ -254,7 +283,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 				ui.Spinner.Suffix = ""
 				ui.StartSpinner()
 			case "Test":
-				err = watchTestPhase(a, buildStatus)
+				err = watchTestPhase(a, buildStatus, out)
 				if err != nil {
 					return err
 				}
@@ -262,7 +291,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 				ui.Spinner.Suffix = ""
 				ui.StartSpinner()
 			case "Release":
-				err = watchReleasePhase(a, buildStatus)
+				err = watchReleasePhase(a, buildStatus, out)
 				if err != nil {
 					return err
 				}
@@ -270,7 +299,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 				ui.Spinner.Suffix = ""
 				ui.StartSpinner()
 			case "Postdeploy":
-				err = watchPostdeployPhase(a, buildStatus)
+				err = watchPostdeployPhase(a, buildStatus, out)
 				if err != nil {
 					return err
 				}
@@ -278,7 +307,7 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 				ui.Spinner.Suffix = ""
 				ui.StartSpinner()
 			case "Deploy":
-				err = watchDeployPhase(a, buildStatus)
+				err = watchDeployPhase(a, buildStatus, out)
 				if err != nil {
 					return err
 				}
@@ -304,7 +333,10 @@ func watchBuild(a *app.App, buildStatus *app.BuildStatus) error {
 	}
 
 	ui.Spinner.Stop()
-	printSuccess(fmt.Sprintf("build #%d deployed successfully", buildStatus.BuildNumber))
+
+	if out.text() {
+		printSuccess(fmt.Sprintf("build #%d deployed successfully", buildStatus.BuildNumber))
+	}
 
 	return nil
 }
@@ -313,10 +345,17 @@ func logMarker(name string) string {
 	return fmt.Sprintf("#*#*#*#*#*# apppack-%s #*#*#*#*#*#", name)
 }
 
-func printLogLine(line string) {
+// printLogLine prints a line of the logs of the build phase
+func (o *buildOutput) printLogLine(phase, line string) {
+	if o.stopped.Load() {
+		return
+	}
+
 	ui.Spinner.Stop()
 
-	if strings.HasPrefix(line, "===> ") {
+	if o.events != nil {
+		o.events.log(phase, line)
+	} else if strings.HasPrefix(line, "===> ") {
 		fmt.Printf("%s %s\n", aurora.Blue("===>"), aurora.White(strings.SplitN(line, " ", 2)[1]))
 	} else if strings.HasPrefix(line, "Unable to delete previous cache image: DELETE") {
 		// https://github.com/aws/containers-roadmap/issues/1229
@@ -326,22 +365,24 @@ func printLogLine(line string) {
 	}
 }
 
-func S3Log(cfg aws.Config, logURL string) error {
+func S3Log(cfg aws.Config, out *buildOutput, phase, logURL string) error {
 	contents, err := app.S3FromURL(cfg, logURL)
 	if err != nil {
-		printWarning("unable to read log file: " + logURL)
+		if out.text() {
+			printWarning("unable to read log file: " + logURL)
+		}
 
 		return err
 	}
 
 	for _, l := range strings.Split(contents.String(), "\n") {
-		printLogLine(l)
+		out.printLogLine(phase, l)
 	}
 
 	return nil
 }
 
-func StreamEvents(cfg aws.Config, logURL string, marker *string, stopTailing <-chan bool) error {
+func StreamEvents(cfg aws.Config, out *buildOutput, phase, logURL string, marker *string, stopTailing <-chan bool) error {
 	var lastSeenTime *int64
 
 	var seenEventIDs map[string]bool
@@ -450,7 +491,7 @@ func StreamEvents(cfg aws.Config, logURL string, marker *string, stopTailing <-c
 									break
 								}
 
-								printLogLine(message)
+								out.printLogLine(phase, message)
 							} else if markerStart != nil {
 								if *markerStart == strings.TrimSuffix(message, "\n") {
 									logrus.WithFields(logrus.Fields{
@@ -479,7 +520,7 @@ func StreamEvents(cfg aws.Config, logURL string, marker *string, stopTailing <-c
 	}
 }
 
-func watchBuildPhase(a *app.App, buildStatus *app.BuildStatus) error {
+func watchBuildPhase(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	ui.StartSpinner()
 
 	buildStatus, err := a.GetBuildStatus(buildStatus.BuildNumber)
@@ -488,7 +529,7 @@ func watchBuildPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	}
 
 	if strings.HasPrefix(buildStatus.Build.Logs, "s3://") {
-		return S3Log(a.Session, buildStatus.Build.Logs)
+		return S3Log(a.Session, out, "Build", buildStatus.Build.Logs)
 	}
 
 	codebuildSvc := codebuild.NewFromConfig(a.Session)
@@ -509,14 +550,14 @@ func watchBuildPhase(a *app.App, buildStatus *app.BuildStatus) error {
 		switch aws.ToString(build.CurrentPhase) {
 		case "BUILD":
 			if strings.HasPrefix(buildStatus.Build.Logs, "s3://") {
-				return S3Log(a.Session, buildStatus.Build.Logs)
+				return S3Log(a.Session, out, "Build", buildStatus.Build.Logs)
 			}
 
 			if !buildLogTailing {
 				buildLogTailing = true
 
 				go func() {
-					_ = StreamEvents(a.Session, buildStatus.Build.Logs, aws.String("build"), stopTailing)
+					_ = StreamEvents(a.Session, out, "Build", buildStatus.Build.Logs, aws.String("build"), stopTailing)
 				}()
 			}
 		case "SUBMITTED", "QUEUED", "PROVISIONING", "DOWNLOAD_SOURCE", "INSTALL", "PRE_BUILD":
@@ -545,7 +586,7 @@ func watchBuildPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	return nil
 }
 
-func watchTestPhase(a *app.App, buildStatus *app.BuildStatus) error {
+func watchTestPhase(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	ui.StartSpinner()
 
 	stopTailing := make(chan bool)
@@ -556,11 +597,11 @@ func watchTestPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	}
 
 	if strings.HasPrefix(buildStatus.Test.Logs, "s3://") {
-		return S3Log(a.Session, buildStatus.Test.Logs)
+		return S3Log(a.Session, out, "Test", buildStatus.Test.Logs)
 	}
 
 	go func() {
-		_ = StreamEvents(a.Session, buildStatus.Build.Logs, aws.String("test"), stopTailing)
+		_ = StreamEvents(a.Session, out, "Test", buildStatus.Build.Logs, aws.String("test"), stopTailing)
 	}()
 
 	for buildStatus.Test.State == Started {
@@ -576,7 +617,7 @@ func watchTestPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	return nil
 }
 
-func watchReleasePhase(a *app.App, buildStatus *app.BuildStatus) error {
+func watchReleasePhase(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	ui.StartSpinner()
 
 	stopTailing := make(chan bool)
@@ -595,12 +636,12 @@ func watchReleasePhase(a *app.App, buildStatus *app.BuildStatus) error {
 	releaseLogTailing := false
 
 	if strings.HasPrefix(buildStatus.Release.Logs, "s3://") {
-		return S3Log(a.Session, buildStatus.Release.Logs)
+		return S3Log(a.Session, out, "Release", buildStatus.Release.Logs)
 	}
 
 	for buildStatus.Release.State == Started {
 		if len(buildStatus.Release.Arns) > 0 {
-			out, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+			tasks, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
 				Cluster: &a.Settings.Cluster.ARN,
 				Tasks:   []string{buildStatus.Release.Arns[0]},
 			})
@@ -608,12 +649,12 @@ func watchReleasePhase(a *app.App, buildStatus *app.BuildStatus) error {
 				return err
 			}
 
-			status := *out.Tasks[0].LastStatus
+			status := *tasks.Tasks[0].LastStatus
 			if status == "RUNNING" && !releaseLogTailing {
 				releaseLogTailing = true
 
 				go func() {
-					_ = StreamEvents(a.Session, buildStatus.Release.Logs, nil, stopTailing)
+					_ = StreamEvents(a.Session, out, "Release", buildStatus.Release.Logs, nil, stopTailing)
 				}()
 			}
 
@@ -642,7 +683,7 @@ func watchReleasePhase(a *app.App, buildStatus *app.BuildStatus) error {
 }
 
 // TODO DRY with watchReleasePhase. Combine to watchEcsTaskPhase
-func watchPostdeployPhase(a *app.App, buildStatus *app.BuildStatus) error {
+func watchPostdeployPhase(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	ui.StartSpinner()
 
 	stopTailing := make(chan bool)
@@ -661,12 +702,12 @@ func watchPostdeployPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	postdeployLogTailing := false
 
 	if strings.HasPrefix(buildStatus.Postdeploy.Logs, "s3://") {
-		return S3Log(a.Session, buildStatus.Postdeploy.Logs)
+		return S3Log(a.Session, out, "Postdeploy", buildStatus.Postdeploy.Logs)
 	}
 
 	for buildStatus.Postdeploy.State == Started {
 		if len(buildStatus.Postdeploy.Arns) > 0 {
-			out, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+			tasks, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
 				Cluster: &a.Settings.Cluster.ARN,
 				Tasks:   []string{buildStatus.Postdeploy.Arns[0]},
 			})
@@ -674,12 +715,12 @@ func watchPostdeployPhase(a *app.App, buildStatus *app.BuildStatus) error {
 				return err
 			}
 
-			status := *out.Tasks[0].LastStatus
+			status := *tasks.Tasks[0].LastStatus
 			if status == "RUNNING" && !postdeployLogTailing {
 				postdeployLogTailing = true
 
 				go func() {
-					_ = StreamEvents(a.Session, buildStatus.Postdeploy.Logs, nil, stopTailing)
+					_ = StreamEvents(a.Session, out, "Postdeploy", buildStatus.Postdeploy.Logs, nil, stopTailing)
 				}()
 			}
 
@@ -704,7 +745,45 @@ func watchPostdeployPhase(a *app.App, buildStatus *app.BuildStatus) error {
 	return nil
 }
 
-func streamEcsServiceEvents(a *app.App, buildStatus *app.BuildStatus) error {
+// printServiceEvents prints the events of the services which haven't been seen, oldest first.
+// Events before the start time (in Unix seconds) are skipped.
+func (o *buildOutput) printServiceEvents(services []ecstypes.Service, start int64, seenEventIDs map[string]bool) {
+	for _, service := range services {
+		eventCount := len(service.Events)
+		for i := range service.Events {
+			// iterate slice in reverse
+			event := service.Events[eventCount-1-i]
+			if _, seen := seenEventIDs[*event.Id]; seen {
+				continue
+			} else if event.CreatedAt.Unix() < start {
+				logrus.WithFields(logrus.Fields{
+					"apppack": start,
+					"ecs":     event.CreatedAt.Unix(),
+				}).Debug("skipping event before deploy")
+
+				seenEventIDs[*event.Id] = true
+
+				continue
+			}
+
+			seenEventIDs[*event.Id] = true
+
+			if o.stopped.Load() {
+				return
+			}
+
+			ui.Spinner.Stop()
+
+			if o.events != nil {
+				o.events.deploy(aws.ToString(service.ServiceName), aws.ToString(event.Message), aws.ToTime(event.CreatedAt))
+			} else {
+				fmt.Printf("%s\n", *event.Message)
+			}
+		}
+	}
+}
+
+func streamEcsServiceEvents(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	buildStatus, err := a.GetBuildStatus(buildStatus.BuildNumber)
 	if err != nil {
 		return err
@@ -734,30 +813,7 @@ func streamEcsServiceEvents(a *app.App, buildStatus *app.BuildStatus) error {
 			return err
 		}
 
-		for _, service := range serviceStatus.Services {
-			eventCount := len(service.Events)
-			for i := range service.Events {
-				// iterate slice in reverse
-				event := service.Events[eventCount-1-i]
-				if _, seen := seenEventIDs[*event.Id]; seen {
-					continue
-				} else if event.CreatedAt.Unix() < buildStatus.Deploy.Start {
-					logrus.WithFields(logrus.Fields{
-						"apppack": buildStatus.Deploy.Start,
-						"ecs":     event.CreatedAt.Unix(),
-					}).Debug("skipping event before deploy")
-
-					seenEventIDs[*event.Id] = true
-
-					continue
-				}
-
-				seenEventIDs[*event.Id] = true
-
-				ui.Spinner.Stop()
-				fmt.Printf("%s\n", *event.Message)
-			}
-		}
+		out.printServiceEvents(serviceStatus.Services, buildStatus.Deploy.Start, seenEventIDs)
 
 		ui.StartSpinner()
 		time.Sleep(5 * time.Second)
@@ -771,10 +827,10 @@ func streamEcsServiceEvents(a *app.App, buildStatus *app.BuildStatus) error {
 	return nil
 }
 
-func watchDeployPhase(a *app.App, buildStatus *app.BuildStatus) error {
+func watchDeployPhase(a *app.App, buildStatus *app.BuildStatus, out *buildOutput) error {
 	ui.StartSpinner()
 
-	return streamEcsServiceEvents(a, buildStatus)
+	return streamEcsServiceEvents(a, buildStatus, out)
 }
 
 // buildCmd represents the build command
@@ -787,13 +843,18 @@ var buildCmd = &cobra.Command{
 
 // buildStartCmd represents the start command
 var buildStartCmd = &cobra.Command{
-	Use:                   "start",
-	Short:                 "start a new build from the latest commit on the branch defined in AppPack",
+	Use:   "start",
+	Short: "start a new build from the latest commit on the branch defined in AppPack",
+	Example: `apppack -a my-app build start --env SKIP_ASSETS=1 --no-cache --watch
+apppack -a my-app build start --local`,
 	DisableFlagsInUseLine: true,
 	Run: func(_ *cobra.Command, _ []string) {
 		ui.StartSpinner()
+		if buildLocal && refFlag != "" {
+			checkErr(errors.New("--ref can't be used with --local"))
+		}
 		var duration int
-		if watchBuildFlag {
+		if watchBuildFlag || buildLocal {
 			duration = MaxSessionDurationSeconds
 		} else {
 			duration = SessionDurationSeconds
@@ -808,16 +869,48 @@ var buildStartCmd = &cobra.Command{
 			_, err = a.ReviewAppExists()
 			checkErr(err)
 		}
-		build, err := a.StartBuild(false, refFlag)
+		if requireValidConfig {
+			problems, err := checkConfig(a, configSchemaFile)
+			checkErr(err)
+			if len(problems) > 0 {
+				ui.Spinner.Stop()
+				printConfigProblems(problems)
+				checkErr(fmt.Errorf("config does not match %s -- build not started", configSchemaFile))
+			}
+		}
+		options, err := buildStartOptions()
 		checkErr(err)
+		if buildLocal {
+			ui.Spinner.Stop()
+			confirmAction("This will build and deploy the files in your working tree, including uncommitted changes.", AppName)
+			ui.StartSpinner()
+			options.SourceLocation, err = uploadLocalSource(a)
+			checkErr(err)
+		}
+		build, err := a.StartBuild(false, options)
+		if err != nil && buildLocal {
+			err = errors.Join(err, a.DeleteLocalSource(options.SourceLocation))
+		}
+		checkErr(err)
+		if buildLocal {
+			go deleteLocalSource(a, aws.ToString(build.Id), options.SourceLocation)
+		}
 		ui.Spinner.Stop()
 		printSuccess("build started")
 		ui.StartSpinner()
 		buildStatus, err := pollBuildStatus(a, int(*build.BuildNumber), 10)
 		checkErr(err)
+		if buildLocal {
+			if err = a.MarkLocalBuild(buildStatus.BuildNumber); err != nil {
+				ui.Spinner.Stop()
+				printWarning("unable to tag the build as local: " + err.Error())
+			} else {
+				buildStatus.Local = true
+			}
+		}
 		ui.Spinner.Stop()
 		printBuild(buildStatus)
-		if watchBuildFlag {
+		if watchBuildFlag || buildLocal {
 			checkErr(watchBuild(a, buildStatus))
 		}
 	},
@@ -833,30 +926,80 @@ var buildWaitCmd = &cobra.Command{
 	},
 }
 
-// buildWaitCmd represents the start command
+var (
+	buildWatchOutput  string
+	buildWatchTimeout time.Duration
+)
+
+// buildWatchCmd represents the watch command
 var buildWatchCmd = &cobra.Command{
-	Use:                   "watch [<build-number>]",
-	Short:                 "watch the progress of the most recent build",
+	Use:   "watch [<build-number>]",
+	Short: "watch the progress of the most recent build",
+	Long: `Watch the progress of a build until it is deployed. Defaults to the most recent build.
+
+With --output jsonl, a JSON object is written per line for each phase transition ("phase"), log line ("log")
+and ECS deploy event ("deploy"), followed by a final "result" event.
+
+The exit code is 2 if the build fails, 3 if the tests fail, 4 if the release, postdeploy or deploy fails,
+and 5 if the build doesn't finish within --timeout.`,
+	Example: `apppack -a my-app build watch
+apppack -a my-app build watch 42 --output jsonl --timeout 30m`,
 	Args:                  cobra.MaximumNArgs(1),
 	DisableFlagsInUseLine: true,
 	Run: func(_ *cobra.Command, args []string) {
+		switch buildWatchOutput {
+		case logsOutputText:
+		case logsOutputJSONL:
+			// keep stdout for events
+			ui.Spinner.Writer = os.Stderr
+			ui.Spinner.WriterFile = os.Stderr
+		default:
+			checkErr(fmt.Errorf("unknown output %q -- must be %s or %s", buildWatchOutput, logsOutputText, logsOutputJSONL))
+		}
 		ui.StartSpinner()
 		a, err := app.Init(AppName, UseAWSCredentials, MaxSessionDurationSeconds)
 		checkErr(err)
-		var build *app.BuildStatus
-		var buildNumber int
-		if len(args) > 0 {
-			buildNumber, err = strconv.Atoi(args[0])
-			checkErr(err)
-			build, err = a.GetBuildStatus(buildNumber)
+		build, err := buildFromArgs(a, args)
+		checkErr(err)
+		ui.Spinner.Stop()
+		out := &buildOutput{}
+		if buildWatchOutput == logsOutputJSONL {
+			out.events = newBuildEventWriter(os.Stdout, build.BuildNumber)
 		} else {
-			build, err = a.GetBuildStatus(-1)
+			printBuild(build)
+			printCommitLog(a.Session, build)
+		}
+
+		// the notification is sent here, once, whether the build finished or the watch timed out
+		done := make(chan error, 1)
+		go func() {
+			done <- followBuild(a, build, out)
+		}()
+		var timeout <-chan time.Time
+		if buildWatchTimeout > 0 {
+			timeout = time.After(buildWatchTimeout)
+		}
+		select {
+		case err = <-done:
+		case <-timeout:
+			out.stop()
+			err = errBuildWatchTimeout
 		}
-		checkErr(err)
 		ui.Spinner.Stop()
-		printBuild(build)
-		printCommitLog(a.Session, build)
-		checkErr(watchBuild(a, build))
+		notifyBuildFinished(a, build, err)
+
+		if out.events != nil {
+			if exitCode := out.events.result(build, err); exitCode != 0 {
+				os.Exit(exitCode)
+			}
+
+			return
+		}
+		if _, exitCode := buildResult(err); exitCode > 1 {
+			printError(err.Error())
+			os.Exit(exitCode)
+		}
+		checkErr(err)
 	},
 }
 
@@ -923,11 +1066,466 @@ var buildStatusCmd = &cobra.Command{
 	},
 }
 
+// buildCancelCmd represents the cancel command
+var buildCancelCmd = &cobra.Command{
+	Use:   "cancel [<build-number>]",
+	Short: "stop an in-progress build",
+	Long: `Stop an in-progress build in CodeBuild. Defaults to the most recent build.
+
+Builds which have started releasing or deploying are not cancelled unless --force is used,
+because stopping them part way through can leave the app partially deployed.`,
+	Example:               "apppack -a my-app build cancel 42",
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		build, err := buildFromArgs(a, args)
+		checkErr(err)
+		if build.DeployStarted() && !forceCancelBuild {
+			checkErr(fmt.Errorf("build #%d has started deploying -- use --force to cancel it anyway", build.BuildNumber))
+		}
+		ui.Spinner.Suffix = fmt.Sprintf(" stopping build #%d", build.BuildNumber)
+		status, err := a.StopBuild(build)
+		ui.Spinner.Stop()
+		ui.Spinner.Suffix = ""
+		checkErr(err)
+		if status != codebuildtypes.StatusTypeStopped {
+			printWarning(fmt.Sprintf("build #%d finished before it could be stopped (%s)", build.BuildNumber, strings.ToLower(string(status))))
+
+			return
+		}
+		printSuccess(fmt.Sprintf("build #%d cancelled", build.BuildNumber))
+	},
+}
+
 var (
-	watchBuildFlag bool
-	refFlag        string
+	watchBuildFlag     bool
+	refFlag            string
+	requireValidConfig bool
+	forceCancelBuild   bool
+	buildEnvFlag       []string
+	buildEnvFile       string
+	buildNoCache       bool
+	buildSkipTests     bool
+	buildLocal         bool
 )
 
+// uploadLocalSource archives the files in the current git working tree which aren't ignored by git and
+// uploads the archive for a local build
+func uploadLocalSource(a *app.App) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	// archive the whole repository, even when run from a subdirectory
+	dir, err := app.LocalSourceRoot(cwd)
+	if err != nil {
+		return "", err
+	}
+
+	ui.Spinner.Suffix = " archiving local files"
+
+	files, err := app.LocalSourceFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := os.CreateTemp("", "apppack-local-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	if err = app.ZipLocalSource(dir, files, archive); err != nil {
+		return "", err
+	}
+
+	info, err := archive.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = archive.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	ui.Spinner.Suffix = fmt.Sprintf(" uploading %d files (%s)", len(files), humanize.Bytes(uint64(info.Size())))
+	location, err := a.UploadLocalSource(archive)
+	ui.Spinner.Suffix = ""
+
+	return location, err
+}
+
+// deleteLocalSource deletes the uploaded source archive once CodeBuild has downloaded it.
+// It runs while the build is watched, so problems are reported on stderr.
+func deleteLocalSource(a *app.App, buildID, location string) {
+	err := a.WaitForSourceDownload(buildID)
+	if err == nil {
+		err = a.DeleteLocalSource(location)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, aurora.Yellow("⚠  unable to delete the local source archive "+location+": "+err.Error()))
+	}
+}
+
+// buildStartOptions builds the overrides for `build start` from the flags.
+// Variables set with --env take precedence over the ones in --env-file.
+func buildStartOptions() (*app.BuildOptions, error) {
+	options := &app.BuildOptions{Ref: refFlag, NoCache: buildNoCache, SkipTests: buildSkipTests, Env: map[string]string{}}
+
+	if buildEnvFile != "" {
+		data, err := os.ReadFile(buildEnvFile)
+		if err != nil {
+			return nil, err
+		}
+
+		options.Env, err = app.DecodeConfig(data, app.FormatDotenv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", buildEnvFile, err)
+		}
+	}
+
+	env, err := app.ParseBuildEnv(buildEnvFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	maps.Copy(options.Env, env)
+
+	return options, nil
+}
+
+// buildDiffJSON is the JSON output of `build diff`
+type buildDiffJSON struct {
+	From                  int                        `json:"from"`
+	To                    int                        `json:"to"`
+	Commits               []buildCommitJSON          `json:"commits"`
+	TaskDefinitionChanges []app.TaskDefinitionChange `json:"task_definition_changes"`
+}
+
+type buildCommitJSON struct {
+	BuildNumber int    `json:"build_number"`
+	Commit      string `json:"commit"`
+	Local       bool   `json:"local"`
+	Log         string `json:"log,omitempty"`
+}
+
+// formatTaskDefinitionChange describes the change on a single line
+func formatTaskDefinitionChange(c *app.TaskDefinitionChange) string {
+	field := c.Field
+	if c.Container != "" {
+		field = fmt.Sprintf("%s %s", c.Container, c.Field)
+	}
+
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("%s: %s %s", field, aurora.Green("+"), c.To)
+	case c.To == "":
+		return fmt.Sprintf("%s: %s %s", field, aurora.Red("-"), c.From)
+	default:
+		return fmt.Sprintf("%s: %s → %s", field, c.From, c.To)
+	}
+}
+
+var buildDiffCmd = &cobra.Command{
+	Use:   "diff <from> <to>",
+	Short: "show what changed between two builds",
+	Long: `Show the commits and the task definition changes (image, environment variable names, CPU and memory)
+between two builds.
+
+If <from> is newer than <to>, the commits listed are the ones which are removed, e.g. when rolling back.`,
+	Example:               "apppack -a my-app build diff 41 42",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		from, err := strconv.Atoi(args[0])
+		checkErr(err)
+		to, err := strconv.Atoi(args[1])
+		checkErr(err)
+		if from == to {
+			checkErr(errors.New("<from> and <to> must be different builds"))
+		}
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		fromBuild, err := a.GetBuildStatus(from)
+		checkErr(err)
+		toBuild, err := a.GetBuildStatus(to)
+		checkErr(err)
+		builds, err := a.BuildsBetween(min(from, to), max(from, to))
+		checkErr(err)
+		changes, err := a.TaskDefinitionChanges(fromBuild, toBuild)
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		// builds of the same commit are only listed once, local builds may include uncommitted changes
+		seen := map[string]bool{}
+		commits := make([]buildCommitJSON, 0, len(builds))
+		for i := range builds {
+			if seen[builds[i].Commit] && !builds[i].Local {
+				continue
+			}
+			seen[builds[i].Commit] = true
+			commit := buildCommitJSON{BuildNumber: builds[i].BuildNumber, Commit: builds[i].Commit, Local: builds[i].Local}
+			if log, err := builds[i].GetCommitLog(a.Session); err == nil {
+				commit.Log = strings.TrimSpace(*log)
+			}
+			commits = append(commits, commit)
+		}
+
+		if AsJSON {
+			checkErr(printJSON(buildDiffJSON{From: from, To: to, Commits: commits, TaskDefinitionChanges: changes}))
+
+			return
+		}
+
+		if from < to {
+			ui.PrintHeaderln(fmt.Sprintf("Commits added from build #%d to build #%d", from, to))
+		} else {
+			ui.PrintHeaderln(fmt.Sprintf("Commits removed from build #%d to build #%d", from, to))
+		}
+		for _, c := range commits {
+			if c.Local {
+				fmt.Println(aurora.Blue(c.Commit), aurora.Faint(fmt.Sprintf("build #%d", c.BuildNumber)), aurora.Magenta("[local]"))
+			} else {
+				fmt.Println(aurora.Blue(c.Commit), aurora.Faint(fmt.Sprintf("build #%d", c.BuildNumber)))
+			}
+			if c.Log == "" {
+				fmt.Print(indentStr)
+				printWarning(fmt.Sprintf("unable to read commit data for build #%d", c.BuildNumber))
+				fmt.Println()
+			} else {
+				fmt.Println(indent(c.Log, indentStr))
+			}
+		}
+
+		fmt.Println()
+		ui.PrintHeaderln("Task definition changes")
+		if len(changes) == 0 {
+			fmt.Println(aurora.Faint("no changes"))
+
+			return
+		}
+		process := ""
+		for i := range changes {
+			if changes[i].Process != process {
+				process = changes[i].Process
+				fmt.Println(aurora.Bold(process))
+			}
+			fmt.Println(indentStr + formatTaskDefinitionChange(&changes[i]))
+		}
+	},
+}
+
+var artifactsDownloadDir string
+
+// buildFromArgs gets the build in the first arg or the most recent build
+func buildFromArgs(a *app.App, args []string) (*app.BuildStatus, error) {
+	buildNumber := -1
+	if len(args) > 0 {
+		var err error
+		if buildNumber, err = strconv.Atoi(args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.GetBuildStatus(buildNumber)
+}
+
+// formatTestSummary describes the test results on a single line
+func formatTestSummary(s *app.TestSummary) string {
+	failed := aurora.Faint(fmt.Sprintf("%d failed", s.Failed))
+	if s.Failed > 0 {
+		failed = aurora.Red(fmt.Sprintf("%d failed", s.Failed))
+	}
+	summary := fmt.Sprintf("%s, %s, %d skipped", aurora.Green(fmt.Sprintf("%d passed", s.Passed)), failed, s.Skipped)
+	if s.Other > 0 {
+		summary += fmt.Sprintf(", %d other", s.Other)
+	}
+
+	return fmt.Sprintf("%s (%d tests in %s)", summary, s.Total, s.Elapsed.Round(time.Millisecond))
+}
+
+var buildArtifactsCmd = &cobra.Command{
+	Use:   "artifacts [<build-number>]",
+	Short: "list or download the artifacts and reports of a build",
+	Long: `List the artifacts CodeBuild stored for a build and the test and code coverage reports it generated.
+Defaults to the most recent build.
+
+With --download, the artifacts are saved in the directory, and the test cases or file coverage of each report
+are saved as JSON (as returned by the CodeBuild API) under reports-json/. The raw report files, e.g. JUnit XML,
+are only downloaded if the buildspec lists them as artifacts.`,
+	Example: `apppack -a my-app build artifacts 42
+apppack -a my-app build artifacts 42 --download ./build-42`,
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		build, err := buildFromArgs(a, args)
+		checkErr(err)
+		artifacts, err := a.BuildArtifacts(build)
+		checkErr(err)
+		reports, err := a.BuildReports(build)
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		if artifactsDownloadDir != "" {
+			for i := range artifacts {
+				ui.StartSpinner()
+				ui.Spinner.Suffix = " downloading " + artifacts[i].Path
+				filename, err := a.DownloadArtifact(&artifacts[i], artifactsDownloadDir)
+				ui.Spinner.Stop()
+				checkErr(err)
+				fmt.Println(filename)
+			}
+			for i := range reports {
+				ui.StartSpinner()
+				ui.Spinner.Suffix = " downloading " + reports[i].Name
+				filename, err := a.DownloadReportJSON(&reports[i], artifactsDownloadDir)
+				ui.Spinner.Stop()
+				checkErr(err)
+				fmt.Println(filename)
+			}
+			ui.Spinner.Suffix = ""
+			printSuccess(fmt.Sprintf("downloaded %d artifacts and %d reports as JSON to %s", len(artifacts), len(reports), artifactsDownloadDir))
+
+			return
+		}
+
+		if AsJSON {
+			checkErr(printJSON(struct {
+				Artifacts []app.BuildArtifact `json:"artifacts"`
+				Reports   []app.BuildReport   `json:"reports"`
+			}{artifacts, reports}))
+
+			return
+		}
+
+		ui.PrintHeaderln(fmt.Sprintf("Artifacts for build #%d", build.BuildNumber))
+		if len(artifacts) == 0 {
+			fmt.Println(aurora.Faint("no artifacts"))
+		}
+		for _, artifact := range artifacts {
+			fmt.Printf("%s %s\n", artifact.Path, aurora.Faint(fmt.Sprintf("%s, %s", artifact.Name, humanize.Bytes(uint64(artifact.Size)))))
+		}
+		fmt.Println()
+		ui.PrintHeaderln("Reports")
+		if len(reports) == 0 {
+			fmt.Println(aurora.Faint("no reports"))
+		}
+		for _, report := range reports {
+			fmt.Printf("%s %s\n", report.Name, aurora.Faint(strings.ToLower(fmt.Sprintf("%s, %s", report.Type, report.Status))))
+			if report.Tests != nil {
+				fmt.Println(indentStr + formatTestSummary(report.Tests))
+			}
+			if report.Coverage != nil {
+				fmt.Printf("%s%.1f%% lines, %.1f%% branches covered\n", indentStr, report.Coverage.LinePercentage, report.Coverage.BranchPercentage)
+			}
+		}
+	},
+}
+
+// testReportJSON is the JSON output of `build test-report`
+type testReportJSON struct {
+	BuildNumber int               `json:"build_number"`
+	Summary     app.TestSummary   `json:"summary"`
+	Reports     []app.BuildReport `json:"reports"`
+	Failures    []testFailureJSON `json:"failures"`
+}
+
+type testFailureJSON struct {
+	Report  string `json:"report"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+var buildTestReportCmd = &cobra.Command{
+	Use:   "test-report [<build-number>]",
+	Short: "summarize the test results of a build",
+	Long: `Summarize the passed, failed and skipped tests in the CodeBuild test reports of a build and list the failures.
+Defaults to the most recent build.`,
+	Example:               "apppack -a my-app build test-report 42 --json",
+	Args:                  cobra.MaximumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(_ *cobra.Command, args []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		build, err := buildFromArgs(a, args)
+		checkErr(err)
+		reports, err := a.BuildReports(build)
+		checkErr(err)
+		result := testReportJSON{BuildNumber: build.BuildNumber, Reports: []app.BuildReport{}, Failures: []testFailureJSON{}}
+		for _, report := range reports {
+			if report.Tests == nil {
+				continue
+			}
+			result.Reports = append(result.Reports, report)
+			result.Summary.Add(report.Tests)
+			if report.Tests.Failed == 0 {
+				continue
+			}
+			for _, status := range []string{"FAILED", "ERROR"} {
+				testCases, err := a.ReportTestCases(report.ARN, status)
+				checkErr(err)
+				for _, tc := range testCases {
+					name := aws.ToString(tc.Name)
+					if prefix := aws.ToString(tc.Prefix); prefix != "" {
+						name = prefix + " " + name
+					}
+					result.Failures = append(result.Failures, testFailureJSON{
+						Report:  report.Name,
+						Name:    name,
+						Status:  aws.ToString(tc.Status),
+						Message: aws.ToString(tc.Message),
+					})
+				}
+			}
+		}
+		ui.Spinner.Stop()
+
+		if AsJSON {
+			checkErr(printJSON(result))
+
+			return
+		}
+
+		if len(result.Reports) == 0 {
+			printWarning(fmt.Sprintf("build #%d has no test reports", build.BuildNumber))
+			fmt.Println()
+
+			return
+		}
+		ui.PrintHeaderln(fmt.Sprintf("Test results for build #%d", build.BuildNumber))
+		for _, report := range result.Reports {
+			fmt.Printf("%s %s\n", report.Name, aurora.Faint(strings.ToLower(report.Status)))
+			fmt.Println(indentStr + formatTestSummary(report.Tests))
+		}
+		if len(result.Failures) > 0 {
+			fmt.Println()
+			ui.PrintHeaderln("Failures")
+			for _, f := range result.Failures {
+				fmt.Printf("%s %s %s\n", aurora.Red("✖"), f.Name, aurora.Faint(f.Report))
+				if f.Message != "" {
+					fmt.Println(indent(strings.TrimSpace(f.Message), indentStr))
+				}
+			}
+		}
+		if len(result.Reports) > 1 {
+			fmt.Println()
+			fmt.Println(aurora.Bold("Total"), formatTestSummary(&result.Summary))
+		}
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(buildCmd)
 	buildCmd.PersistentFlags().StringVarP(&AppName, "app-name", "a", "", "app name (required)")
@@ -939,9 +1537,26 @@ func init() {
 	buildStartCmd.Flags().BoolVar(&watchBuildFlag, "wait", false, "watch build process")
 	buildStartCmd.Flags().MarkDeprecated("wait", "please use --watch instead")
 	buildStartCmd.Flags().StringVar(&refFlag, "ref", "", "git reference (branch, tag, or commit hash) to build")
+	buildStartCmd.Flags().BoolVar(&requireValidConfig, "require-valid-config", false, "don't start the build unless the config matches the schema (see `config check`)")
+	buildStartCmd.Flags().StringVar(&configSchemaFile, "schema", app.DefaultConfigSchemaFile, "path to the config schema file")
+	buildStartCmd.Flags().StringArrayVar(&buildEnvFlag, "env", []string{}, "environment variable for the build as KEY=value (can be repeated)")
+	buildStartCmd.Flags().StringVar(&buildEnvFile, "env-file", "", "dotenv file with environment variables for the build")
+	buildStartCmd.Flags().BoolVar(&buildNoCache, "no-cache", false, "build without the CodeBuild cache")
+	buildStartCmd.Flags().BoolVar(&buildLocal, "local", false, "build the files in the current git working tree (except the ones ignored by git) instead of pushed commits, and watch the build")
+	addNotifyFlags(buildStartCmd.Flags())
+	buildStartCmd.Flags().BoolVar(&buildSkipTests, "skip-tests", false, "skip the test phase (sets "+app.SkipTestsVariable+"=1, the buildspec must support it)")
 	buildCmd.AddCommand(buildListCmd)
 	buildCmd.AddCommand(buildStatusCmd)
 
 	buildCmd.AddCommand(buildWaitCmd)
 	buildCmd.AddCommand(buildWatchCmd)
+	buildWatchCmd.Flags().StringVarP(&buildWatchOutput, "output", "o", logsOutputText, "output format (text or jsonl)")
+	buildWatchCmd.Flags().DurationVar(&buildWatchTimeout, "timeout", 0, "stop watching and exit if the build hasn't finished after this long (eg. 30m)")
+	addNotifyFlags(buildWatchCmd.Flags())
+	buildCmd.AddCommand(buildCancelCmd)
+	buildCancelCmd.Flags().BoolVar(&forceCancelBuild, "force", false, "cancel the build even if it has started deploying")
+	buildCmd.AddCommand(buildDiffCmd)
+	buildCmd.AddCommand(buildArtifactsCmd)
+	buildArtifactsCmd.Flags().StringVar(&artifactsDownloadDir, "download", "", "directory to download the artifacts and the reports as JSON to")
+	buildCmd.AddCommand(buildTestReportCmd)
 }
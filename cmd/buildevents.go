@@ -0,0 +1,209 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+)
+
+// exit codes of `build watch`
+const (
+	exitBuildFailed  = 2
+	exitTestFailed   = 3
+	exitDeployFailed = 4
+	exitTimeout      = 5
+)
+
+// results of a watched build
+const (
+	buildResultSuccess      = "success"
+	buildResultBuildFailed  = "build_failed"
+	buildResultTestFailed   = "test_failed"
+	buildResultDeployFailed = "deploy_failed"
+	buildResultTimeout      = "timeout"
+	buildResultError        = "error"
+)
+
+// buildOutput is where followBuild writes the progress of a build
+type buildOutput struct {
+	// events writes the progress of `build watch --output jsonl`. It is nil for text output.
+	events *buildEventWriter
+	// stopped is set when the caller stops waiting for followBuild, e.g. after `build watch --timeout`,
+	// so nothing is written after the result
+	stopped atomic.Bool
+}
+
+// text reports whether progress is written as text
+func (o *buildOutput) text() bool {
+	return o.events == nil && !o.stopped.Load()
+}
+
+// stop stops any more progress being written
+func (o *buildOutput) stop() {
+	o.stopped.Store(true)
+}
+
+// phaseFailedError is returned by watchBuild when a phase of the build fails
+type phaseFailedError struct {
+	phase *app.BuildPhase
+}
+
+func (e *phaseFailedError) Error() string {
+	return fmt.Sprintf("%s failed at %s", e.phase.Name, e.phase.Phase.EndTime().Local().Format(timeFmt))
+}
+
+// errBuildWatchTimeout is returned when a build doesn't finish within `build watch --timeout`
+var errBuildWatchTimeout = errors.New("timed out waiting for the build to finish")
+
+// buildResult classifies the error returned from watching a build into a result and an exit code
+func buildResult(err error) (string, int) {
+	var phaseErr *phaseFailedError
+
+	switch {
+	case err == nil:
+		return buildResultSuccess, 0
+	case errors.Is(err, errBuildWatchTimeout):
+		return buildResultTimeout, exitTimeout
+	case errors.As(err, &phaseErr):
+		switch phaseErr.phase.Name {
+		case "Build", "Finalize":
+			return buildResultBuildFailed, exitBuildFailed
+		case "Test":
+			return buildResultTestFailed, exitTestFailed
+		default:
+			return buildResultDeployFailed, exitDeployFailed
+		}
+	default:
+		return buildResultError, 1
+	}
+}
+
+// buildEvent is a single line of `build watch --output jsonl`
+type buildEvent struct {
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	BuildNumber int       `json:"build_number"`
+	Phase       string    `json:"phase,omitempty"`
+	State       string    `json:"state,omitempty"`
+	Service     string    `json:"service,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	ExitCode    *int      `json:"exit_code,omitempty"`
+	Duration    float64   `json:"duration_seconds,omitempty"`
+}
+
+// buildEventWriter writes build events as JSON lines. It is safe to use from the log tailing goroutines.
+type buildEventWriter struct {
+	mu          sync.Mutex
+	enc         *json.Encoder
+	buildNumber int
+	started     time.Time
+	// phaseStates is the last state written for each phase
+	phaseStates map[string]string
+	// finished is set once the result is written, nothing is written after it
+	finished bool
+}
+
+func newBuildEventWriter(w io.Writer, buildNumber int) *buildEventWriter {
+	return &buildEventWriter{
+		enc:         json.NewEncoder(w),
+		buildNumber: buildNumber,
+		started:     time.Now(),
+		phaseStates: map[string]string{},
+	}
+}
+
+func (w *buildEventWriter) write(event *buildEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.finished {
+		return
+	}
+
+	event.BuildNumber = w.buildNumber
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	_ = w.enc.Encode(event)
+	w.finished = event.Type == "result"
+}
+
+// phases writes an event for each phase whose state changed since the last call
+func (w *buildEventWriter) phases(buildStatus *app.BuildStatus) {
+	for _, p := range buildStatus.NamedPhases() {
+		w.mu.Lock()
+		changed := p.Phase.State != "" && w.phaseStates[p.Name] != p.Phase.State
+		w.phaseStates[p.Name] = p.Phase.State
+		w.mu.Unlock()
+
+		if !changed {
+			continue
+		}
+
+		event := &buildEvent{Type: "phase", Phase: p.Name, State: p.Phase.State, Time: p.Phase.StartTime()}
+		if p.Phase.State != app.PhaseInProgress {
+			event.Time = p.Phase.EndTime()
+		}
+
+		w.write(event)
+	}
+}
+
+func (w *buildEventWriter) log(phase, line string) {
+	w.write(&buildEvent{Type: "log", Phase: phase, Message: line})
+}
+
+func (w *buildEventWriter) deploy(service, message string, createdAt time.Time) {
+	w.write(&buildEvent{Type: "deploy", Phase: "Deploy", Service: service, Message: message, Time: createdAt})
+}
+
+func (w *buildEventWriter) result(buildStatus *app.BuildStatus, err error) int {
+	result, exitCode := buildResult(err)
+	event := &buildEvent{
+		Type:     "result",
+		Result:   result,
+		ExitCode: &exitCode,
+		Duration: time.Since(w.started).Seconds(),
+	}
+
+	if buildStatus != nil {
+		event.Commit = buildStatus.Commit
+	}
+
+	var phaseErr *phaseFailedError
+	if errors.As(err, &phaseErr) {
+		event.Phase = phaseErr.phase.Name
+	}
+
+	if err != nil {
+		event.Message = err.Error()
+	}
+
+	w.write(event)
+
+	return exitCode
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+)
+
+func TestBuildResult(t *testing.T) {
+	t.Parallel()
+
+	failed := func(name string) error {
+		return &phaseFailedError{phase: &app.BuildPhase{Name: name, Phase: &app.BuildPhaseDetail{State: app.PhaseFailed}}}
+	}
+
+	tests := []struct {
+		err      error
+		result   string
+		exitCode int
+	}{
+		{nil, buildResultSuccess, 0},
+		{failed("Build"), buildResultBuildFailed, exitBuildFailed},
+		{failed("Test"), buildResultTestFailed, exitTestFailed},
+		{failed("Release"), buildResultDeployFailed, exitDeployFailed},
+		{failed("Deploy"), buildResultDeployFailed, exitDeployFailed},
+		{fmt.Errorf("watching: %w", errBuildWatchTimeout), buildResultTimeout, exitTimeout},
+		{errors.New("access denied"), buildResultError, 1},
+	}
+
+	for _, tt := range tests {
+		result, exitCode := buildResult(tt.err)
+		if result != tt.result || exitCode != tt.exitCode {
+			t.Errorf("%v: expected %s (%d), got %s (%d)", tt.err, tt.result, tt.exitCode, result, exitCode)
+		}
+	}
+}
+
+func readBuildEvents(t *testing.T, buf *bytes.Buffer) []buildEvent {
+	t.Helper()
+
+	var events []buildEvent
+
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var event buildEvent
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestBuildEventWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w := newBuildEventWriter(buf, 42)
+	start := time.Now().Add(-time.Minute).Unix()
+	status := &app.BuildStatus{BuildNumber: 42, Commit: "abc123"}
+	status.Build = app.BuildPhaseDetail{State: app.PhaseInProgress, Start: start}
+
+	w.phases(status)
+	w.log("Build", "===> building")
+	// unchanged phases aren't written again
+	w.phases(status)
+	status.Build.State = app.PhaseSuccess
+	status.Build.End = start + 30
+	status.Test = app.BuildPhaseDetail{State: app.PhaseFailed, Start: start + 30, End: start + 40}
+	w.phases(status)
+
+	exitCode := w.result(status, &phaseFailedError{phase: &app.BuildPhase{Name: "Test", Phase: &status.Test}})
+	if exitCode != exitTestFailed {
+		t.Errorf("expected exit code %d, got %d", exitTestFailed, exitCode)
+	}
+	// nothing is written after the result
+	w.log("Test", "late line")
+	w.deploy("web", "late event", time.Now())
+
+	events := readBuildEvents(t, buf)
+	expected := []struct{ eventType, phase, state string }{
+		{"phase", "Build", app.PhaseInProgress},
+		{"log", "Build", ""},
+		{"phase", "Build", app.PhaseSuccess},
+		{"phase", "Test", app.PhaseFailed},
+		{"result", "Test", ""},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+
+	for i, e := range expected {
+		if events[i].Type != e.eventType || events[i].Phase != e.phase || events[i].State != e.state || events[i].BuildNumber != 42 {
+			t.Errorf("expected %s %s %s event at %d, got %+v", e.eventType, e.phase, e.state, i, events[i])
+		}
+	}
+
+	result := events[len(events)-1]
+	if result.Result != buildResultTestFailed || result.Commit != "abc123" || result.ExitCode == nil || *result.ExitCode != exitTestFailed {
+		t.Errorf("unexpected result event %+v", result)
+	}
+}
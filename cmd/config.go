@@ -18,18 +18,24 @@ package cmd
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	osexec "os/exec"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/apppackio/apppack/app"
 	"github.com/apppackio/apppack/ui"
+	"github.com/apppackio/apppack/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/smithy-go"
+	"github.com/charmbracelet/huh"
 	"github.com/juju/ansiterm"
+	"github.com/logrusorgru/aurora"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
@@ -63,27 +69,127 @@ var getCmd = &cobra.Command{
 	},
 }
 
+// parseConfigAssignments parses <variable>=<value> arguments
+func parseConfigAssignments(args []string) (map[string]string, error) {
+	values := map[string]string{}
+
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		if !found {
+			return nil, fmt.Errorf("argument %q should be in the form <variable>=<value>", arg)
+		}
+
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("%s is set more than once", name)
+		}
+
+		if err := app.ValidateConfigKey(name); err != nil {
+			return nil, err
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// restartServices does a rolling restart of all the services for the app
+func restartServices(a *app.App) error {
+	services, err := a.GetServices()
+	if err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
+		printWarning("no services to restart")
+
+		return nil
+	}
+
+	for _, service := range services {
+		ui.Spinner.Suffix = " restarting " + service
+		if err = a.RestartProcess(service, false); err != nil {
+			return err
+		}
+	}
+
+	ui.Spinner.Suffix = ""
+
+	return nil
+}
+
+var (
+	configSetFromFile string
+	configSetFormat   string
+	configSetRestart  bool
+	configSetSecure   bool
+	configSetKMSKey   string
+)
+
 // setCmd represents the config set command
 var setCmd = &cobra.Command{
-	Use:                   "set <variable>=<value>",
-	Short:                 "set the value of a single config variable",
+	Use:   "set <variable>=<value>...",
+	Short: "set the value of one or more config variables",
+	Long: `Set the value of one or more config variables.
+
+All the variables are validated before any are written. If writing any variable fails,
+the variables already written are restored to their previous values.
+
+Values are encrypted (stored as SecureString parameters) unless --secure=false is given.
+Use --kms-key to encrypt them with a customer managed KMS key instead of the AWS managed key.`,
 	DisableFlagsInUseLine: true,
-	Args:                  cobra.ExactArgs(1),
-	Example:               "apppack -a my-app config set ENVIRONMENT=production",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if configSetFromFile == "" {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+
+		return nil
+	},
+	Example: `apppack -a my-app config set ENVIRONMENT=production
+apppack -a my-app config set ENVIRONMENT=production DEBUG=false --restart
+apppack -a my-app config set --from-file .env --format dotenv
+apppack -a my-app config set --kms-key alias/my-key API_TOKEN=abc123`,
 	Run: func(_ *cobra.Command, args []string) {
-		if !strings.Contains(args[0], "=") {
-			checkErr(errors.New("argument should be in the form <variable>=<value>"))
+		if !configSetSecure && configSetKMSKey != "" {
+			checkErr(errors.New("--kms-key can't be used with --secure=false"))
+		}
+		values, err := parseConfigAssignments(args)
+		checkErr(err)
+		if configSetFromFile != "" {
+			data, err := os.ReadFile(configSetFromFile)
+			checkErr(err)
+			fileValues, err := app.DecodeConfig(data, configSetFormat)
+			if err != nil {
+				checkErr(fmt.Errorf("unable to parse %s: %w", configSetFromFile, err))
+			}
+			for name, value := range fileValues {
+				if _, ok := values[name]; !ok {
+					values[name] = value
+				}
+			}
+		}
+		if len(values) == 0 {
+			checkErr(errors.New("no config variables to set"))
 		}
-		parts := strings.SplitN(args[0], "=", 2)
-		name := parts[0]
-		value := parts[1]
 		ui.StartSpinner()
 		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
 		checkErr(err)
-		err = a.SetConfig(name, value, true)
+		err = a.SetConfigBatch(values, app.ConfigStorage{PlainText: !configSetSecure, KMSKeyID: configSetKMSKey})
 		checkErr(err)
 		ui.Spinner.Stop()
-		printSuccess("stored config variable " + name)
+		if len(values) == 1 {
+			for name := range values {
+				printSuccess("stored config variable " + name)
+			}
+		} else {
+			printSuccess(fmt.Sprintf("stored %d config variables", len(values)))
+		}
+		if configSetRestart {
+			ui.StartSpinner()
+			checkErr(restartServices(a))
+			ui.Spinner.Stop()
+			printSuccess("triggered rolling restart of services")
+		}
 	},
 }
 
@@ -98,29 +204,65 @@ var unsetCmd = &cobra.Command{
 		name := args[0]
 		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
 		checkErr(err)
-		svc := ssm.NewFromConfig(a.Session)
-		_, err = svc.DeleteParameter(context.Background(), &ssm.DeleteParameterInput{
-			Name: aws.String(fmt.Sprintf("%s%s", a.ConfigPrefix(), args[0])),
-		})
+		err = a.UnsetConfig(name)
 		ui.Spinner.Stop()
 		checkErr(err)
 		printSuccess("removed config variable " + name)
 	},
 }
 
+var configListReveal []string
+
+// revealAllConfig is the value of a bare --reveal flag
+const revealAllConfig = "*"
+
+// parseRevealFlag returns whether all values are revealed or the names of the variables to reveal.
+// Naming variables makes --reveal an allowlist, a bare --reveal reveals everything.
+func parseRevealFlag(flagValues, args []string) (bool, []string) {
+	revealAll := false
+
+	var reveal []string
+
+	for _, name := range append(flagValues, args...) {
+		if name == revealAllConfig {
+			revealAll = true
+		} else {
+			reveal = append(reveal, name)
+		}
+	}
+
+	return revealAll && len(reveal) == 0, reveal
+}
+
 // configListCmd represents the list command
 var configListCmd = &cobra.Command{
-	Use:                   "list",
-	Short:                 "list all config variables and values",
+	Use:   "list",
+	Short: "list all config variables and values",
+	Long: `List all config variables. Values are masked unless they are revealed with --reveal.
+
+A bare --reveal shows all values except well-known secrets (` + strings.Join(app.SensitiveConfigPatterns, ", ") + `).
+Those are only shown when they are revealed by name.`,
+	Example: `apppack -a my-app config list
+apppack -a my-app config list --reveal
+apppack -a my-app config list --reveal DATABASE_URL SECRET_KEY`,
 	DisableFlagsInUseLine: true,
-	Args:                  cobra.ExactArgs(0),
-	Run: func(_ *cobra.Command, _ []string) {
+	Args: func(cmd *cobra.Command, args []string) error {
+		// variable names can follow a bare --reveal, e.g. --reveal FOO BAR
+		if !flagIsSet(cmd.Flags(), "reveal") {
+			return cobra.ExactArgs(0)(cmd, args)
+		}
+
+		return nil
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		revealAll, reveal := parseRevealFlag(configListReveal, args)
 		ui.StartSpinner()
 		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
 		checkErr(err)
 		configVars, err := a.GetConfig()
 		checkErr(err)
 		ui.Spinner.Stop()
+		configVars = configVars.Masked(revealAll, reveal)
 
 		if AsJSON {
 			buf, err := configVars.ToJSON()
@@ -143,26 +285,45 @@ var configListCmd = &cobra.Command{
 
 		if a.IsReviewApp() {
 			fmt.Println()
-			a.ReviewApp = nil
 			ui.StartSpinner()
-			parameters, err := a.GetConfig()
+			withInherited, err := a.GetConfigWithInherited()
 			checkErr(err)
 			ui.Spinner.Stop()
-			parameters.ToConsole(w)
+			// only show the pipeline's variables which the review app doesn't override
+			own := map[string]bool{}
+			for _, configVar := range configVars {
+				own[configVar.Name] = true
+			}
+			var inherited app.ConfigVariables
+			for _, configVar := range withInherited {
+				if !own[configVar.Name] {
+					inherited = append(inherited, configVar)
+				}
+			}
+			inherited = inherited.Masked(revealAll, reveal)
+			inherited.ToConsole(w)
 			ui.PrintHeaderln(a.Name + " Config Vars (inherited)")
 			checkErr(w.Flush())
 		}
 	},
 }
 
-var includeManagedVars bool
+var (
+	includeManagedVars   bool
+	configExportFormat   string
+	configExportWithType bool
+)
 
 // configExportCmd represents the config export command
 var configExportCmd = &cobra.Command{
 	Use:                   "export",
-	Short:                 "export the config variables to JSON",
+	Short:                 "export the config variables to JSON, dotenv, shell, or YAML",
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.ExactArgs(0),
+	Example: `apppack -a my-app config export > config.json
+apppack -a my-app config export --format dotenv > .env
+apppack -a my-app config export --with-types > config.json
+eval "$(apppack -a my-app config export --format shell)"`,
 	Run: func(_ *cobra.Command, _ []string) {
 		ui.StartSpinner()
 		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
@@ -170,40 +331,55 @@ var configExportCmd = &cobra.Command{
 		configVars, err := a.GetConfigWithManaged()
 		checkErr(err)
 		ui.Spinner.Stop()
+		if !includeManagedVars {
+			configVars = configVars.Unmanaged()
+		}
 		var buf *bytes.Buffer
-		if includeManagedVars {
-			buf, err = configVars.ToJSON()
+		if configExportWithType {
+			buf, err = configVars.ToFormatWithTypes(configExportFormat)
 		} else {
-			buf, err = configVars.ToJSONUnmanaged()
+			buf, err = configVars.ToFormat(configExportFormat)
 		}
 		checkErr(err)
 		fmt.Println(buf.String())
 	},
 }
 
-var importConfigOverride bool
+var (
+	importConfigOverride bool
+	configImportFormat   string
+	configImportWithType bool
+)
 
 // configImportCmd represents the config export command
 var configImportCmd = &cobra.Command{
 	Use:                   "import <file>",
-	Short:                 "import config variables from a JSON file",
+	Short:                 "import config variables from a JSON, dotenv, shell, or YAML file",
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.ExactArgs(1),
+	Example: `apppack -a my-app config import --format dotenv .env
+apppack -a my-app config import --with-types config.json`,
 	Run: func(_ *cobra.Command, args []string) {
-		ui.StartSpinner()
-		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
-		checkErr(err)
 		data, err := os.ReadFile(args[0])
+		checkErr(err)
+		var config map[string]string
+		var plainText []string
+		if configImportWithType {
+			config, plainText, err = app.DecodeConfigWithTypes(data, configImportFormat)
+		} else {
+			config, err = app.DecodeConfig(data, configImportFormat)
+		}
 		if err != nil {
-			fmt.Print(err)
+			checkErr(fmt.Errorf("unable to parse %s: %w", args[0], err))
 		}
-		config := make(map[string]string)
-		err = json.Unmarshal(data, &config)
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
 		checkErr(err)
 		imported := 0
 		skipped := 0
 		for key, val := range config {
-			err = a.SetConfig(key, val, importConfigOverride)
+			storage := app.ConfigStorage{PlainText: slices.Contains(plainText, key)}
+			err = a.SetConfigWithStorage(key, val, importConfigOverride, storage)
 			if err != nil {
 				var apiErr smithy.APIError
 				if errors.As(err, &apiErr) {
@@ -227,6 +403,795 @@ var configImportCmd = &cobra.Command{
 	},
 }
 
+var (
+	configRunOnly           []string
+	configRunExcept         []string
+	configRunAWSCredentials bool
+)
+
+// configRunCmd represents the config run command
+var configRunCmd = &cobra.Command{
+	Use:   "run -- <command>...",
+	Short: "run a local command with the app's config variables in its environment",
+	Long: `Run a local command with the app's config variables (including AppPack managed variables) merged into its environment.
+
+Use --only and --except to limit which variables are set. Both accept glob patterns, e.g. AWS_*.`,
+	Example: `apppack -a my-app config run -- python manage.py shell
+apppack -a my-app config run --except DATABASE_URL -- env
+apppack -a my-app config run --with-aws-credentials -- ./scripts/backfill.sh`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		duration := SessionDurationSeconds
+		if configRunAWSCredentials {
+			duration = MaxSessionDurationSeconds
+		}
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, duration)
+		checkErr(err)
+		configVars, err := a.GetConfigWithInherited()
+		checkErr(err)
+		ui.Spinner.Stop()
+		env := environ(os.Environ())
+		for _, configVar := range configVars.Filter(configRunOnly, configRunExcept) {
+			env.Set(configVar.Name, configVar.Value)
+		}
+		if configRunAWSCredentials {
+			checkErr(env.SetAWSCredentials(a.Session))
+		}
+		checkErr(execWithEnviron(args[0], args[1:], env))
+	},
+}
+
+var configMigrateKMSKey string
+
+// configMigrateToSecureCmd represents the config migrate-to-secure command
+var configMigrateToSecureCmd = &cobra.Command{
+	Use:   "migrate-to-secure",
+	Short: "encrypt config variables which are stored as plain text",
+	Long: `*Requires admin permissions.*
+Convert config variables stored as plain text String parameters to encrypted SecureString parameters in place.
+
+AppPack managed variables are left unchanged because they are maintained by CloudFormation.`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, _ []string) {
+		ui.StartSpinner()
+		cfg, err := adminSession(SessionDurationSeconds)
+		checkErr(err)
+		a, err := app.InitWithSession(AppName, cfg)
+		checkErr(err)
+		configVars, err := a.GetConfigWithManaged()
+		checkErr(err)
+		ui.Spinner.Stop()
+		configVars = configVars.Unmanaged()
+		plainText := configVars.PlainText()
+		if len(plainText) == 0 {
+			printSuccess("all config variables are encrypted")
+
+			return
+		}
+		ui.PrintHeaderln("Plain Text Config Vars")
+		for _, name := range plainText {
+			fmt.Println(name)
+		}
+		fmt.Println()
+		confirmAction(fmt.Sprintf("This will encrypt %d config variables.", len(plainText)), AppName)
+		storage := app.ConfigStorage{KMSKeyID: configMigrateKMSKey}
+		ui.StartSpinner()
+		for _, configVar := range configVars {
+			if configVar.IsPlainText() {
+				checkErr(a.SetConfigWithStorage(configVar.Name, configVar.Value, true, storage))
+			}
+		}
+		ui.Spinner.Stop()
+		printSuccess(fmt.Sprintf("encrypted %d config variables", len(plainText)))
+	},
+}
+
+var (
+	configDiffShowValues bool
+	configDiffAll        bool
+)
+
+// configDifferenceJSON is a JSON-serializable representation of an app.ConfigDifference.
+type configDifferenceJSON struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+func toConfigDifferenceJSON(d *app.ConfigDifference) configDifferenceJSON {
+	return configDifferenceJSON{
+		Name:   d.Name,
+		Change: d.Change,
+		From:   d.OldValue,
+		To:     d.NewValue,
+	}
+}
+
+// maskConfigDifferences replaces the values of the differences with a mask
+func maskConfigDifferences(diffs []*app.ConfigDifference) {
+	for _, d := range diffs {
+		if d.OldValue != "" {
+			d.OldValue = app.MaskedValue
+		}
+
+		if d.NewValue != "" {
+			d.NewValue = app.MaskedValue
+		}
+	}
+}
+
+// configForDiff loads the config variables of an app to compare, skipping managed variables unless includeManaged is set
+func configForDiff(a *app.App, includeManaged bool) (app.ConfigVariables, error) {
+	if includeManaged {
+		return a.GetConfig()
+	}
+
+	configVars, err := a.GetConfigWithManaged()
+	if err != nil {
+		return nil, err
+	}
+
+	return configVars.Unmanaged(), nil
+}
+
+func printConfigDifferences(diffs []*app.ConfigDifference) {
+	for _, d := range diffs {
+		switch d.Change {
+		case app.ConfigAdded:
+			fmt.Printf("%s %s %s\n", aurora.Green("+"), aurora.Green(d.Name+":"), d.NewValue)
+		case app.ConfigRemoved:
+			fmt.Printf("%s %s %s\n", aurora.Red("-"), aurora.Red(d.Name+":"), d.OldValue)
+		case app.ConfigChanged:
+			fmt.Printf("%s %s %s %s %s\n", aurora.Yellow("~"), aurora.Yellow(d.Name+":"), d.OldValue, aurora.Faint("→"), d.NewValue)
+		}
+	}
+}
+
+// configDiffCmd represents the config diff command
+var configDiffCmd = &cobra.Command{
+	Use:   "diff <other-app>",
+	Short: "show config variables which differ from another app",
+	Long: `Compare the config variables of the app with another app, pipeline, or review app.
+
+Variables only set on <other-app> are shown as added, variables missing from <other-app> are shown as removed.
+Review apps are referenced as <pipeline>:<pr-number>. Values are masked unless --show-values is used.`,
+	Example: `apppack -a my-app-staging config diff my-app-production
+apppack -a my-pipeline:123 config diff my-pipeline`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		other, err := app.Init(args[0], UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		configVars, err := configForDiff(a, configDiffAll)
+		checkErr(err)
+		otherConfigVars, err := configForDiff(other, configDiffAll)
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		diffs := configVars.Diff(otherConfigVars)
+		if !configDiffShowValues {
+			maskConfigDifferences(diffs)
+		}
+
+		if AsJSON {
+			wrapped := make([]configDifferenceJSON, 0, len(diffs))
+			for _, d := range diffs {
+				wrapped = append(wrapped, toConfigDifferenceJSON(d))
+			}
+			checkErr(printJSON(wrapped))
+
+			return
+		}
+
+		if len(diffs) == 0 {
+			printSuccess(fmt.Sprintf("no config differences between %s and %s", AppName, args[0]))
+
+			return
+		}
+
+		ui.PrintHeaderln(fmt.Sprintf("%s → %s Config Diff", AppName, args[0]))
+		printConfigDifferences(diffs)
+	},
+}
+
+var configHistoryShowValues bool
+
+// configVersionJSON is a JSON-serializable representation of an app.ConfigVersion.
+type configVersionJSON struct {
+	Version    int64     `json:"version"`
+	Value      string    `json:"value"`
+	ModifiedAt time.Time `json:"modified_at"`
+	ModifiedBy string    `json:"modified_by"`
+}
+
+func toConfigVersionJSON(v *app.ConfigVersion, showValue bool) configVersionJSON {
+	value := app.MaskedValue
+	if showValue {
+		value = v.Value
+	}
+
+	return configVersionJSON{
+		Version:    v.Version,
+		Value:      value,
+		ModifiedAt: v.LastModifiedDate,
+		ModifiedBy: v.ModifiedBy(),
+	}
+}
+
+// configHistoryCmd represents the config history command
+var configHistoryCmd = &cobra.Command{
+	Use:                   "history <variable>",
+	Short:                 "show the previous values of a config variable",
+	Long:                  "Show the stored versions of a config variable, newest first. Values are masked unless --show-values is used.",
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		history, err := a.ConfigHistory(args[0])
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		wrapped := make([]configVersionJSON, 0, len(history))
+		for i := len(history) - 1; i >= 0; i-- {
+			wrapped = append(wrapped, toConfigVersionJSON(history[i], configHistoryShowValues))
+		}
+
+		if AsJSON {
+			checkErr(printJSON(wrapped))
+
+			return
+		}
+
+		if len(wrapped) == 0 {
+			checkErr(fmt.Errorf("no history found for %s", args[0]))
+		}
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w := ansiterm.NewTabWriter(os.Stdout, 8, 8, 2, ' ', 0)
+
+		if isatty.IsTerminal(os.Stdout.Fd()) {
+			w.SetColorCapable(true)
+		}
+
+		ui.PrintHeaderln(args[0] + " History")
+		for _, v := range wrapped {
+			w.SetForeground(ansiterm.Green)
+			fmt.Fprintf(w, "v%d", v.Version)
+			w.SetForeground(ansiterm.Default)
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", v.ModifiedAt.Local().Format(timeFmt), v.ModifiedBy, v.Value)
+		}
+		checkErr(w.Flush())
+	},
+}
+
+var configAuditSince string
+
+// configChangeJSON is a JSON-serializable representation of an app.ConfigChange.
+type configChangeJSON struct {
+	Name      string    `json:"name"`
+	Action    string    `json:"action"`
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Version   int64     `json:"version,omitempty"`
+	EventID   string    `json:"event_id"`
+	SourceIP  string    `json:"source_ip"`
+	UserAgent string    `json:"user_agent"`
+}
+
+func toConfigChangeJSON(c *app.ConfigChange) configChangeJSON {
+	return configChangeJSON{
+		Name:      c.Name,
+		Action:    c.Action,
+		Time:      c.Time,
+		User:      c.User,
+		Version:   c.Version,
+		EventID:   c.EventID,
+		SourceIP:  c.SourceIP,
+		UserAgent: c.UserAgent,
+	}
+}
+
+// configAuditCmd represents the config audit command
+var configAuditCmd = &cobra.Command{
+	Use:   "audit [<variable>]",
+	Short: "show who changed config variables and when",
+	Long: `Show the changes made to config variables, newest first, from the CloudTrail event history.
+
+CloudTrail keeps 90 days of event history and events can take a few minutes to appear.`,
+	Example: `apppack -a my-app config audit
+apppack -a my-app config audit SECRET_KEY --since 30d`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MaximumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		since, err := TimeFromFlag(configAuditSince)
+		checkErr(err)
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		changes, truncated, err := a.ConfigAudit(name, since)
+		checkErr(err)
+		ui.Spinner.Stop()
+		if truncated {
+			fmt.Fprintln(os.Stderr, aurora.Yellow("⚠  only the most recent CloudTrail events were searched -- use --since or name a variable to see older changes"))
+		}
+
+		if AsJSON {
+			wrapped := make([]configChangeJSON, 0, len(changes))
+			for _, c := range changes {
+				wrapped = append(wrapped, toConfigChangeJSON(c))
+			}
+			checkErr(printJSON(wrapped))
+
+			return
+		}
+
+		if len(changes) == 0 {
+			printWarning("no config changes found since " + since.Local().Format(timeFmt))
+
+			return
+		}
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w := ansiterm.NewTabWriter(os.Stdout, 8, 8, 2, ' ', 0)
+
+		if isatty.IsTerminal(os.Stdout.Fd()) {
+			w.SetColorCapable(true)
+		}
+
+		ui.PrintHeaderln(AppName + " Config Changes")
+		for _, c := range changes {
+			fmt.Fprintf(w, "%s\t", c.Time.Local().Format(timeFmt))
+			if c.Action == app.ConfigChangeSet {
+				w.SetForeground(ansiterm.Green)
+			} else {
+				w.SetForeground(ansiterm.Red)
+			}
+			fmt.Fprintf(w, "%s", c.Action)
+			w.SetForeground(ansiterm.Default)
+			version := ""
+			if c.Version > 0 {
+				version = fmt.Sprintf("v%d", c.Version)
+			}
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", c.Name, version, c.User)
+		}
+		checkErr(w.Flush())
+	},
+}
+
+var (
+	configRollbackVersion    int64
+	configRollbackAsOf       string
+	configRollbackShowValues bool
+	configRollbackYes        bool
+)
+
+// configRollbackCmd represents the config rollback command
+var configRollbackCmd = &cobra.Command{
+	Use:   "rollback [<variable>]",
+	Short: "restore config variables to a previous value",
+	Long: `Restore a config variable to a previous version (see ` + "`config history`" + `).
+
+Without a variable, --as-of restores all the config variables of the app to their values at that time.
+Variables created since then are removed. Variables which have been deleted since then cannot be restored.
+
+The changes are shown (masked unless --show-values is used) and confirmed before they are applied, unless --yes is used.`,
+	Example: `apppack -a my-app config rollback ENVIRONMENT --version 3
+apppack -a my-app config rollback ENVIRONMENT --as-of 2h
+apppack -a my-app config rollback --as-of 2022-12-01T14:52:00Z`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		versionSet := flagIsSet(cmd.Flags(), "version")
+		if versionSet == (configRollbackAsOf != "") {
+			checkErr(errors.New("exactly one of --version or --as-of is required"))
+		}
+		if versionSet && len(args) == 0 {
+			checkErr(errors.New("--version requires a variable"))
+		}
+		var asOf time.Time
+		var err error
+		if configRollbackAsOf != "" {
+			asOf, err = TimeFromFlag(configRollbackAsOf)
+			checkErr(err)
+		}
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+
+		if len(args) == 1 {
+			name := args[0]
+			history, err := a.ConfigHistory(name)
+			checkErr(err)
+			var v *app.ConfigVersion
+			if versionSet {
+				v = app.FindConfigVersion(history, configRollbackVersion)
+				if v == nil {
+					checkErr(fmt.Errorf("version %d of %s not found", configRollbackVersion, name))
+				}
+			} else {
+				v = app.ConfigVersionAsOf(history, asOf)
+				if v == nil {
+					checkErr(fmt.Errorf("%s did not exist at %s", name, asOf.Local().Format(timeFmt)))
+				}
+			}
+			version := v.Version
+			ui.Spinner.Stop()
+			diffs := []*app.ConfigDifference{{Name: name, Change: app.ConfigChanged, OldValue: history[len(history)-1].Value, NewValue: v.Value}}
+			if diffs[0].OldValue == diffs[0].NewValue {
+				printSuccess(fmt.Sprintf("%s already has the value of version %d", name, version))
+
+				return
+			}
+			if !configRollbackShowValues {
+				maskConfigDifferences(diffs)
+			}
+			ui.PrintHeaderln(fmt.Sprintf("Restoring %s to version %d", name, version))
+			printConfigDifferences(diffs)
+			fmt.Println()
+			if !configRollbackYes {
+				confirmAction("This will change 1 config variable.", AppName)
+			}
+			ui.StartSpinner()
+			_, err = a.RollbackConfig(name, version)
+			checkErr(err)
+			ui.Spinner.Stop()
+			printSuccess(fmt.Sprintf("restored config variable %s to version %d", name, version))
+
+			return
+		}
+
+		current, err := a.GetConfigWithManaged()
+		checkErr(err)
+		current = current.Unmanaged()
+		restored, err := a.ConfigAsOf(current, asOf)
+		checkErr(err)
+		ui.Spinner.Stop()
+		diffs := current.Diff(restored)
+		if len(diffs) == 0 {
+			printSuccess("config is unchanged since " + asOf.Local().Format(timeFmt))
+
+			return
+		}
+		if !configRollbackShowValues {
+			maskConfigDifferences(diffs)
+		}
+		ui.PrintHeaderln("Restoring config as of " + asOf.Local().Format(timeFmt))
+		printConfigDifferences(diffs)
+		fmt.Println()
+		if !configRollbackYes {
+			confirmAction(fmt.Sprintf("This will change %d config variables.", len(diffs)), AppName)
+		}
+		ui.StartSpinner()
+		restoredValues := map[string]string{}
+		for _, configVar := range restored {
+			restoredValues[configVar.Name] = configVar.Value
+		}
+		values := map[string]string{}
+		var removed []string
+		for _, d := range diffs {
+			if d.Change == app.ConfigRemoved {
+				removed = append(removed, d.Name)
+			} else {
+				values[d.Name] = restoredValues[d.Name]
+			}
+		}
+		checkErr(a.RestoreConfig(values, removed))
+		ui.Spinner.Stop()
+		printSuccess(fmt.Sprintf("restored %d config variables", len(diffs)))
+	},
+}
+
+var configSchemaFile string
+
+type configProblemJSON struct {
+	Name        string `json:"name"`
+	Problem     string `json:"problem"`
+	Message     string `json:"message"`
+	Description string `json:"description,omitempty"`
+}
+
+func toConfigProblemJSON(p *app.ConfigProblem) configProblemJSON {
+	return configProblemJSON{
+		Name:        p.Name,
+		Problem:     p.Problem,
+		Message:     p.Message,
+		Description: p.Description,
+	}
+}
+
+// checkConfig validates the app's config against the schema in filename
+func checkConfig(a *app.App, filename string) ([]*app.ConfigProblem, error) {
+	schema, err := app.LoadConfigSchema(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	configVars, err := a.GetConfigWithInherited()
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.Validate(configVars), nil
+}
+
+func printConfigProblems(problems []*app.ConfigProblem) {
+	for _, p := range problems {
+		line := fmt.Sprintf("%s %s %s", aurora.Red("✖"), aurora.Bold(p.Name+":"), p.Message)
+		if p.Problem == app.ConfigProblemUnknown {
+			line = fmt.Sprintf("%s %s %s", aurora.Yellow("?"), aurora.Bold(p.Name+":"), p.Message)
+		}
+
+		if p.Description != "" {
+			line += fmt.Sprintf(" %s", aurora.Faint("("+p.Description+")"))
+		}
+
+		fmt.Println(line)
+	}
+}
+
+// configCheckCmd represents the config check command
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "validate the config variables against a schema file",
+	Long: `Validate the config variables against the schema in ` + app.DefaultConfigSchemaFile + ` in the current directory (or --schema).
+
+The schema lists the variables the app expects with an optional description and format (url, integer, or regex):
+
+  variables:
+    SECRET_KEY:
+      description: Django secret key
+      format: regex
+      pattern: ".{50,}"
+    SENTRY_DSN:
+      format: url
+      optional: true
+
+Missing required variables, values with the wrong format, and variables not listed in the schema are reported.
+AppPack managed variables (e.g. DATABASE_URL) are never reported as unknown.`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, _ []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		problems, err := checkConfig(a, configSchemaFile)
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		if AsJSON {
+			wrapped := make([]configProblemJSON, 0, len(problems))
+			for _, p := range problems {
+				wrapped = append(wrapped, toConfigProblemJSON(p))
+			}
+			checkErr(printJSON(wrapped))
+		} else if len(problems) == 0 {
+			printSuccess("config matches " + configSchemaFile)
+		} else {
+			ui.PrintHeaderln(AppName + " Config Problems")
+			printConfigProblems(problems)
+		}
+
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+const configEditHeader = `# Edit the config variables for %s below, one <variable>=<value> per line (dotenv format).
+# Remove a line to unset the variable. Save and exit the editor to review the changes.
+`
+
+// configEditFile renders the config variables as a dotenv file for editing.
+// Managed variables are included as comments since they can't be changed.
+func configEditFile(name string, configVars app.ConfigVariables) ([]byte, error) {
+	buf := bytes.NewBufferString(fmt.Sprintf(configEditHeader, name))
+
+	var managed app.ConfigVariables
+
+	for _, configVar := range configVars {
+		if configVar.Managed {
+			managed = append(managed, configVar)
+		}
+	}
+
+	if len(managed) > 0 {
+		buf.WriteString("#\n# Managed by AppPack (read-only):\n")
+
+		managedBuf, err := managed.ToFormat(app.FormatDotenv)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(managedBuf.String(), "\n") {
+			buf.WriteString("#   " + line + "\n")
+		}
+	}
+
+	buf.WriteString("\n")
+
+	unmanaged := configVars.Unmanaged()
+
+	unmanagedBuf, err := unmanaged.ToFormat(app.FormatDotenv)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.Write(unmanagedBuf.Bytes())
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// configEditChanges parses the edited file and returns the changes to the unmanaged config variables
+func configEditChanges(configVars app.ConfigVariables, edited []byte) ([]*app.ConfigDifference, error) {
+	values, err := app.DecodeConfig(edited, app.FormatDotenv)
+	if err != nil {
+		return nil, err
+	}
+
+	var editedVars app.ConfigVariables
+
+	for _, configVar := range configVars {
+		if configVar.Managed {
+			if _, ok := values[configVar.Name]; ok {
+				return nil, fmt.Errorf("%s is managed by AppPack and cannot be edited", configVar.Name)
+			}
+		}
+	}
+
+	for name, value := range values {
+		editedVars = append(editedVars, &app.ConfigVariable{Name: name, Value: value})
+	}
+
+	unmanaged := configVars.Unmanaged()
+
+	return unmanaged.Diff(editedVars), nil
+}
+
+// editFile opens the file in the user's preferred editor and waits for it to exit
+func editFile(filename string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// editors are often configured with arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := osexec.Command(parts[0], append(parts[1:], filename)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// editConfigContents writes contents to a temporary file, opens it in the user's editor and returns the
+// file's name and edited contents. The file is removed if anything fails before it is read back.
+func editConfigContents(contents []byte) (string, []byte, error) {
+	file, err := os.CreateTemp("", "apppack-config-*.env")
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, err = file.Write(contents)
+	err = errors.Join(err, file.Close())
+
+	if err == nil {
+		err = editFile(file.Name())
+	}
+
+	var edited []byte
+	if err == nil {
+		edited, err = os.ReadFile(file.Name())
+	}
+
+	if err != nil {
+		return "", nil, errors.Join(err, os.Remove(file.Name()))
+	}
+
+	return file.Name(), edited, nil
+}
+
+// ConfigEditConfirmForm builds the confirmation form displayed before applying edited config.
+// Returns the form and a pointer to the confirmed bool value.
+func ConfigEditConfirmForm(count int) (*huh.Form, *bool) {
+	confirmed := false
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Apply %d config changes?", count)).
+				Affirmative("Yes").
+				Negative("No").
+				Value(&confirmed),
+		),
+	)
+
+	return form, &confirmed
+}
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "edit all config variables in your editor",
+	Long: `Open the config variables in $VISUAL or $EDITOR (dotenv format).
+
+After the editor exits, a summary of the changes is shown for confirmation before they are applied.
+Variables managed by AppPack (e.g. DATABASE_URL) are shown as comments and cannot be edited.`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, _ []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		configVars, err := a.GetConfigWithManaged()
+		checkErr(err)
+		ui.Spinner.Stop()
+
+		contents, err := configEditFile(AppName, configVars)
+		checkErr(err)
+		filename, edited, err := editConfigContents(contents)
+		checkErr(err)
+		diffs, err := configEditChanges(configVars, edited)
+		if err != nil {
+			// the file is kept so the edits aren't lost
+			checkErr(fmt.Errorf("%w (your edits are saved in %s)", err, filename))
+		}
+		// the file holds every value in plain text, so don't leave it behind
+		checkErr(os.Remove(filename))
+		if len(diffs) == 0 {
+			printSuccess("no config changes")
+
+			return
+		}
+
+		values := map[string]string{}
+		var removed []string
+		for _, d := range diffs {
+			if d.Change == app.ConfigRemoved {
+				removed = append(removed, d.Name)
+			} else {
+				values[d.Name] = d.NewValue
+			}
+		}
+
+		maskConfigDifferences(diffs)
+		ui.PrintHeaderln(AppName + " Config Changes")
+		printConfigDifferences(diffs)
+		fmt.Println()
+		form, confirmed := ConfigEditConfirmForm(len(diffs))
+		checkErr(form.Run())
+		if !*confirmed {
+			checkErr(errors.New("aborting due to user input"))
+		}
+
+		ui.StartSpinner()
+		checkErr(a.RestoreConfig(values, removed))
+		ui.Spinner.Stop()
+		printSuccess(fmt.Sprintf("applied %d config changes", len(diffs)))
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.PersistentFlags().StringVarP(&AppName, "app-name", "a", "", "app name (required)")
@@ -240,14 +1205,72 @@ func init() {
 
 	configCmd.AddCommand(getCmd)
 	configCmd.AddCommand(setCmd)
+	setCmd.Flags().StringVar(&configSetFromFile, "from-file", "", "read variables from a file (variables given as arguments take precedence)")
+	setCmd.Flags().StringVar(&configSetFormat, "format", app.FormatJSON, "format of --from-file ("+strings.Join(app.ConfigFormats, ", ")+")")
+	setCmd.Flags().BoolVar(&configSetRestart, "restart", false, "restart all services after the variables are set")
+	setCmd.Flags().BoolVar(&configSetSecure, "secure", true, "encrypt the values (SecureString parameters)")
+	setCmd.Flags().StringVar(&configSetKMSKey, "kms-key", "", "ID, ARN, or alias of the KMS key used to encrypt the values")
 	configCmd.AddCommand(unsetCmd)
 	configCmd.AddCommand(configListCmd)
+	configListCmd.Flags().StringSliceVar(&configListReveal, "reveal", nil, "show values, optionally only for the given variables")
+	configListCmd.Flags().Lookup("reveal").NoOptDefVal = revealAllConfig
 	configCmd.AddCommand(configExportCmd)
 	configExportCmd.Flags().BoolVar(&includeManagedVars,
 		"all",
 		false,
 		"include AppPack managed variables (e.g. DATABASE_URL)",
 	)
+	configExportCmd.Flags().StringVar(&configExportFormat, "format", app.FormatJSON, "output format ("+strings.Join(app.ConfigFormats, ", ")+")")
+	configExportCmd.Flags().BoolVar(&configExportWithType, "with-types", false, "record which variables are not encrypted so import preserves them")
+
+	configCmd.AddCommand(configEditCmd)
+
+	configCmd.AddCommand(configRunCmd)
+	configRunCmd.Flags().StringSliceVar(&configRunOnly, "only", nil, "only set these variables")
+	configRunCmd.Flags().StringSliceVar(&configRunExcept, "except", nil, "don't set these variables")
+	configRunCmd.Flags().BoolVar(&configRunAWSCredentials, "with-aws-credentials", false, "also set the app's AWS credentials in the environment")
+
+	configCmd.AddCommand(configMigrateToSecureCmd)
+	configMigrateToSecureCmd.Flags().StringVar(&configMigrateKMSKey, "kms-key", "", "ID, ARN, or alias of the KMS key used to encrypt the values")
+	configMigrateToSecureCmd.Flags().StringVarP(&AccountIDorAlias, "account", "c", "", utils.AccountFlagHelpText)
+	configMigrateToSecureCmd.Flags().StringVar(&region, "region", "", "AWS region of app")
+
+	configCmd.AddCommand(configCheckCmd)
+	configCheckCmd.Flags().StringVar(&configSchemaFile, "schema", app.DefaultConfigSchemaFile, "path to the config schema file")
+
+	configCmd.AddCommand(configDiffCmd)
+	configDiffCmd.Flags().BoolVar(&configDiffShowValues, "show-values", false, "show config values instead of masking them")
+	configDiffCmd.Flags().BoolVar(&configDiffAll,
+		"all",
+		false,
+		"include AppPack managed variables (e.g. DATABASE_URL)",
+	)
+
+	configCmd.AddCommand(configHistoryCmd)
+	configHistoryCmd.Flags().BoolVar(&configHistoryShowValues, "show-values", false, "show config values instead of masking them")
+
+	configCmd.AddCommand(configAuditCmd)
+	configAuditCmd.Flags().StringVar(
+		&configAuditSince,
+		"since",
+		"7d",
+		`show changes after this time
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+
+	configCmd.AddCommand(configRollbackCmd)
+	configRollbackCmd.Flags().Int64Var(&configRollbackVersion, "version", 0, "version of the variable to restore")
+	configRollbackCmd.Flags().StringVar(
+		&configRollbackAsOf,
+		"as-of",
+		"",
+		`restore the value(s) at this point in time
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+	configRollbackCmd.Flags().BoolVar(&configRollbackShowValues, "show-values", false, "show config values instead of masking them")
+	configRollbackCmd.Flags().BoolVarP(&configRollbackYes, "yes", "y", false, "restore without asking for confirmation")
 
 	configCmd.AddCommand(configImportCmd)
 	configImportCmd.Flags().BoolVar(&importConfigOverride,
@@ -255,4 +1278,6 @@ func init() {
 		false,
 		"overwrite variables if they already exist",
 	)
+	configImportCmd.Flags().StringVar(&configImportFormat, "format", app.FormatJSON, "input format ("+strings.Join(app.ConfigFormats, ", ")+")")
+	configImportCmd.Flags().BoolVar(&configImportWithType, "with-types", false, "read a file written by export --with-types and keep unencrypted variables unencrypted")
 }
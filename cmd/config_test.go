@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/apppackio/apppack/ui/uitest"
+)
+
+func TestParseConfigAssignments(t *testing.T) {
+	t.Parallel()
+
+	values, err := parseConfigAssignments([]string{"A=1", "B=has=equals", "C="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"A": "1", "B": "has=equals", "C": ""}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	for _, args := range [][]string{{"A"}, {"A=1", "A=2"}, {"1A=1"}, {"=1"}} {
+		if _, err := parseConfigAssignments(args); err == nil {
+			t.Errorf("expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestConfigEditFile(t *testing.T) {
+	t.Parallel()
+
+	configVars := app.ConfigVariables{
+		{Name: "DATABASE_URL", Value: "postgres://db", Managed: true},
+		{Name: "FOO", Value: "bar"},
+		{Name: "MULTI", Value: "a\nb"},
+	}
+
+	contents, err := configEditFile("my-app", configVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Edit the config variables for my-app below, one <variable>=<value> per line (dotenv format).
+# Remove a line to unset the variable. Save and exit the editor to review the changes.
+#
+# Managed by AppPack (read-only):
+#   DATABASE_URL=postgres://db
+
+FOO=bar
+MULTI="a\nb"
+`
+	if string(contents) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, contents)
+	}
+
+	// an unedited file has no changes
+	diffs, err := configEditChanges(configVars, contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 0 {
+		t.Errorf("expected no changes, got %d", len(diffs))
+	}
+}
+
+func TestConfigEditChanges(t *testing.T) {
+	t.Parallel()
+
+	configVars := app.ConfigVariables{
+		{Name: "DATABASE_URL", Value: "postgres://db", Managed: true},
+		{Name: "FOO", Value: "bar"},
+		{Name: "REMOVED", Value: "1"},
+	}
+
+	diffs, err := configEditChanges(configVars, []byte("FOO=baz\nNEW=1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []app.ConfigDifference{
+		{Name: "FOO", Change: app.ConfigChanged, OldValue: "bar", NewValue: "baz"},
+		{Name: "NEW", Change: app.ConfigAdded, NewValue: "1"},
+		{Name: "REMOVED", Change: app.ConfigRemoved, OldValue: "1"},
+	}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d changes, got %d", len(expected), len(diffs))
+	}
+
+	for i, d := range diffs {
+		if *d != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], *d)
+		}
+	}
+
+	if _, err = configEditChanges(configVars, []byte("DATABASE_URL=postgres://other\n")); err == nil {
+		t.Error("expected error when editing a managed variable, got nil")
+	}
+}
+
+func TestConfigEditConfirmForm(t *testing.T) {
+	form, confirmed := ConfigEditConfirmForm(2)
+	tm := uitest.RunForm(t, form)
+	uitest.TypeAndSubmit(tm, "y")
+	uitest.WaitDone(t, tm)
+
+	if !*confirmed {
+		t.Error("expected confirmed to be true")
+	}
+}
+
+func TestParseRevealFlag(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		flagValues []string
+		args       []string
+		revealAll  bool
+		reveal     []string
+	}{
+		{flagValues: nil, args: nil, revealAll: false, reveal: nil},
+		{flagValues: []string{revealAllConfig}, args: nil, revealAll: true, reveal: nil},
+		{flagValues: []string{revealAllConfig}, args: []string{"FOO", "BAR"}, revealAll: false, reveal: []string{"FOO", "BAR"}},
+		{flagValues: []string{"FOO", "BAR"}, args: nil, revealAll: false, reveal: []string{"FOO", "BAR"}},
+	}
+
+	for _, s := range scenarios {
+		revealAll, reveal := parseRevealFlag(s.flagValues, s.args)
+		if revealAll != s.revealAll || !reflect.DeepEqual(reveal, s.reveal) {
+			t.Errorf("%v %v: expected %t %v, got %t %v", s.flagValues, s.args, s.revealAll, s.reveal, revealAll, reveal)
+		}
+	}
+}
@@ -23,9 +23,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/apppackio/apppack/app"
+	"github.com/apppackio/apppack/logs"
 	"github.com/apppackio/apppack/ui"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -139,39 +139,52 @@ var dbDumpCmd = &cobra.Command{
 func taskLogs(cfg aws.Config, task *ecstypes.Task) error {
 	ecsSvc := ecs.NewFromConfig(cfg)
 
-	taskDefn, err := ecsSvc.DescribeTaskDefinition(context.Background(), &ecs.DescribeTaskDefinitionInput{
-		TaskDefinition: task.TaskDefinitionArn,
+	updatedTaskResp, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+		Cluster: task.ClusterArn,
+		Tasks:   []string{*task.TaskArn},
 	})
 	if err != nil {
 		return err
 	}
 
-	updatedTaskResp, err := ecsSvc.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
-		Cluster: task.ClusterArn,
-		Tasks:   []string{*task.TaskArn},
-	})
+	query, err := taskLogsQuery(cfg, &updatedTaskResp.Tasks[0])
 	if err != nil {
 		return err
 	}
 
-	task = &updatedTaskResp.Tasks[0]
+	return printLogs([]*logs.Reader{logs.NewReader(cfg, *query)}, &logsOutput, false)
+}
+
+// taskLogsQuery returns a query for the task's log stream, using the awslogs options of its task definition
+func taskLogsQuery(cfg aws.Config, task *ecstypes.Task) (*logs.Query, error) {
+	taskDefn, err := ecs.NewFromConfig(cfg).DescribeTaskDefinition(context.Background(), &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: task.TaskDefinitionArn,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	containerDefn := taskDefn.TaskDefinition.ContainerDefinitions[0]
 	logConfig := containerDefn.LogConfiguration
+	if logConfig == nil || logConfig.LogDriver != ecstypes.LogDriverAwslogs {
+		return nil, fmt.Errorf("task definition %s doesn't send logs to CloudWatch Logs", aws.ToString(task.TaskDefinitionArn))
+	}
 	taskArnParts := strings.Split(*task.TaskArn, "/")
 	taskID := taskArnParts[len(taskArnParts)-1]
-	sawConfig.Group = logConfig.Options["awslogs-group"]
-	sawConfig.Start = task.StartedAt.Format(time.RFC3339)
-	// Use prefix-based filtering instead of directly setting streams
-	// since saw library uses v1 SDK types for Streams
-	sawConfig.Prefix = fmt.Sprintf("%s/%s/%s",
-		logConfig.Options["awslogs-stream-prefix"],
-		*containerDefn.Name,
-		taskID)
-
-	newBlade(cfg).GetEvents()
+	// tasks which failed to start don't have a start time
+	start := task.CreatedAt
+	if task.StartedAt != nil {
+		start = task.StartedAt
+	}
 
-	return nil
+	return &logs.Query{
+		Group: logConfig.Options["awslogs-group"],
+		Start: aws.ToTime(start),
+		Streams: []string{fmt.Sprintf("%s/%s/%s",
+			logConfig.Options["awslogs-stream-prefix"],
+			*containerDefn.Name,
+			taskID)},
+	}, nil
 }
 
 var postgresLoadJobs int
@@ -17,68 +17,67 @@ package cmd
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"net/url"
-	"reflect"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"time"
-	"unsafe"
 
-	"github.com/TylerBrock/saw/blade"
-	sawconfig "github.com/TylerBrock/saw/config"
 	"github.com/apppackio/apppack/app"
+	"github.com/apppackio/apppack/logs"
 	"github.com/apppackio/apppack/ui"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsv1 "github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/dustin/go-humanize"
+	"github.com/juju/ansiterm"
+	"github.com/logrusorgru/aurora"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	sawConfig       sawconfig.Configuration
-	sawOutputConfig sawconfig.OutputConfiguration
-	logsStart       string
-	logsEnd         string
+	logsQuery        logs.Query
+	logsOutput       logs.Output
+	logsStart        string
+	logsEnd          string
+	logsWhere        []string
+	logsOutputFormat string
 )
 
-// newBlade is a hack to get a Blade instance with our AWS session
-func newBlade(cfg aws.Config) *blade.Blade {
-	// Convert v2 credentials to v1 session for saw library
-	creds, err := cfg.Credentials.Retrieve(context.Background())
-	if err != nil {
-		panic(err)
-	}
-
-	// Create v1 session with credentials from v2 config
-	sess, err := session.NewSession(&awsv1.Config{
-		Region: awsv1.String(cfg.Region),
-		Credentials: credentials.NewStaticCredentials(
-			creds.AccessKeyID,
-			creds.SecretAccessKey,
-			creds.SessionToken,
-		),
-	})
-	if err != nil {
-		panic(err)
+const (
+	logsOutputText  = "text"
+	logsOutputJSONL = "jsonl"
+)
+
+// parseStructuredFlags sets the structured log options from the --where and --output flags
+func parseStructuredFlags(output *logs.Output, where []string, format string) error {
+	for _, expression := range where {
+		c, err := logs.ParseCondition(expression)
+		if err != nil {
+			return err
+		}
+
+		output.Where = append(output.Where, c)
 	}
 
-	b := blade.Blade{}
-	setField := func(name string, value interface{}) {
-		field := reflect.ValueOf(&b).Elem().FieldByName(name)
-		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(value))
+	switch format {
+	case logsOutputText:
+	case logsOutputJSONL:
+		output.JSONLines = true
+	default:
+		return fmt.Errorf("unknown output %q -- must be %s or %s", format, logsOutputText, logsOutputJSONL)
 	}
-	setField("cwl", cloudwatchlogs.New(sess))
-	setField("config", &sawConfig)
-	setField("output", &sawOutputConfig)
 
-	return &b
+	return nil
 }
 
-// TimeValForSaw converts/validates an AppPack time flag to what Saw expects
+// TimeValForSaw converts/validates an AppPack time flag to a relative duration (e.g. -2h) or RFC3339 timestamp
 func TimeValForSaw(val string) (string, error) {
 	relativeTimeUnits := []string{"s", "m", "h"}
 
@@ -118,14 +117,179 @@ func TimeValForSaw(val string) (string, error) {
 	return val, nil
 }
 
-var followLogs = false
+// TimeFromFlag converts an AppPack time flag (see TimeValForSaw) to a time
+func TimeFromFlag(val string) (time.Time, error) {
+	val, err := TimeValForSaw(val)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if val == "" || val == "now" {
+		return time.Now(), nil
+	}
+
+	if strings.HasPrefix(val, "-") {
+		duration, err := time.ParseDuration(val)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Now().Add(duration), nil
+	}
+
+	return time.Parse(time.RFC3339, val)
+}
+
+// endTimeFromFlag converts an AppPack time flag to the end of a logs.Query, "now" meaning no end
+func endTimeFromFlag(val string) (time.Time, error) {
+	if val == "" || val == "now" {
+		return time.Time{}, nil
+	}
+
+	return TimeFromFlag(val)
+}
+
+// printLogs prints the events read by the readers in timestamp order, continuing to print new events if follow is set
+func printLogs(readers []*logs.Reader, output *logs.Output, follow bool) error {
+	printEvent := func(event *logs.Event) error {
+		if line, ok := output.Render(event); ok {
+			fmt.Println(line)
+		}
+
+		return nil
+	}
+
+	if follow {
+		return logs.FollowAll(context.Background(), readers, printEvent)
+	}
+
+	return logs.EventsAll(context.Background(), readers, printEvent)
+}
+
+var (
+	followLogs   = false
+	logsAppNames []string
+	logsTask     string
+	logsProcess  string
+)
+
+// logsAppName returns the app for the logs subcommands which only read the logs of one app
+func logsAppName() string {
+	if len(logsAppNames) > 1 {
+		checkErr(errors.New("only one --app-name can be used with this command"))
+	}
+
+	return logsAppNames[0]
+}
+
+// logsTaskQuery sets the query to read the log stream of the task selected by the --task or --process flags.
+// Unless --start or --stop are set, the query covers the whole life of the task.
+func logsTaskQuery(a *app.App, query *logs.Query, flags *pflag.FlagSet) error {
+	var (
+		task *ecstypes.Task
+		err  error
+	)
+
+	if logsTask != "" {
+		task, err = a.DescribeTask(logsTask)
+	} else {
+		task, err = a.ProcessTask(logsProcess)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	taskQuery, err := taskLogsQuery(a.Session, task)
+	if err != nil {
+		return err
+	}
+
+	query.Group = taskQuery.Group
+	query.Streams = taskQuery.Streams
+
+	if !flagIsSet(flags, "start") {
+		query.Start = taskQuery.Start
+	}
+
+	if task.StoppedAt != nil && !flagIsSet(flags, "stop") {
+		query.End = *task.StoppedAt
+	}
+
+	return nil
+}
+
+// logsReader creates a reader for the app's logs matching the logs command flags
+func logsReader(name string, duration int, label bool, flags *pflag.FlagSet) (*logs.Reader, error) {
+	a, err := app.Init(name, UseAWSCredentials, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.LoadSettings(); err != nil {
+		return nil, err
+	}
+
+	query := logsQuery
+	query.Group = a.Settings.LogGroup.Name
+
+	if query.Start, err = TimeFromFlag(logsStart); err != nil {
+		return nil, err
+	}
+
+	if query.End, err = endTimeFromFlag(logsEnd); err != nil {
+		return nil, err
+	}
+
+	if logsTask != "" || logsProcess != "" {
+		if err := logsTaskQuery(a, &query, flags); err != nil {
+			return nil, err
+		}
+	} else if a.IsReviewApp() {
+		query.Prefix = fmt.Sprintf("pr%s-%s", *a.ReviewApp, query.Prefix)
+	}
+
+	if label {
+		query.App = name
+	}
+
+	reader := logs.NewReader(a.Session, query)
+
+	if query.Prefix != "" {
+		found, err := reader.HasStreams(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no streams found in %s with prefix %s", query.Group, query.Prefix)
+		}
+	}
+
+	return reader, nil
+}
 
 // logsCmd represents the logs command
 var logsCmd = &cobra.Command{
-	Use:                   "logs",
-	Short:                 "access application logs from Cloudwatch Logs",
+	Use:   "logs",
+	Short: "access application logs from Cloudwatch Logs",
+	Long: `Print the logs of one or more apps.
+
+Use --app-name multiple times to read the logs of several apps at once,
+e.g. an API and the worker it sends jobs to. Events from all the apps are
+interleaved by timestamp and prefixed with the app name.`,
+	Example: `apppack -a my-app logs --start 1h --prefix web
+apppack -a my-app logs -f --where level=error --where 'status>=500' --fields ts,level,msg
+apppack -a my-app logs --start 1d --where 'level!=debug' --output jsonl | jq .msg
+apppack -a api -a worker -a my-pipeline:123 logs -f
+apppack -a my-app logs --process web.2 -f
+apppack -a my-app logs --task 0123456789abcdef0123456789abcdef`,
 	DisableFlagsInUseLine: true,
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
+		checkErr(parseStructuredFlags(&logsOutput, logsWhere, logsOutputFormat))
+		if (logsTask != "" || logsProcess != "") && len(logsAppNames) > 1 {
+			checkErr(errors.New("--task and --process can only be used with one app"))
+		}
 		ui.StartSpinner()
 		var duration int
 		if followLogs {
@@ -133,33 +297,19 @@ var logsCmd = &cobra.Command{
 		} else {
 			duration = SessionDurationSeconds
 		}
-		a, err := app.Init(AppName, UseAWSCredentials, duration)
-		checkErr(err)
-		err = a.LoadSettings()
-		checkErr(err)
-		sawConfig.Group = a.Settings.LogGroup.Name
-		sawOutputConfig.Pretty = !sawOutputConfig.Raw
-		sawConfig.Start, err = TimeValForSaw(logsStart)
-		checkErr(err)
-		sawConfig.End, err = TimeValForSaw(logsEnd)
-		checkErr(err)
-		b := newBlade(a.Session)
-		if a.IsReviewApp() {
-			sawConfig.Prefix = fmt.Sprintf("pr%s-%s", *a.ReviewApp, sawConfig.Prefix)
+		label := len(logsAppNames) > 1
+		if label {
+			logsOutput.Apps = logsAppNames
 		}
-		if sawConfig.Prefix != "" {
-			streams := b.GetLogStreams()
-			if len(streams) == 0 {
-				checkErr(fmt.Errorf("no streams found in %s with prefix %s", sawConfig.Group, sawConfig.Prefix))
-			}
-			sawConfig.Streams = streams
+		readers := make([]*logs.Reader, 0, len(logsAppNames))
+		// each app has its own log group and credentials
+		for _, name := range logsAppNames {
+			reader, err := logsReader(name, duration, label, cmd.Flags())
+			checkErr(err)
+			readers = append(readers, reader)
 		}
 		ui.Spinner.Stop()
-		if followLogs {
-			b.StreamEvents()
-		} else {
-			b.GetEvents()
-		}
+		checkErr(printLogs(readers, &logsOutput, followLogs))
 	},
 }
 
@@ -170,7 +320,7 @@ var logsOpenCmd = &cobra.Command{
 	Long:                  `Generates a presigned URL and opens a web browser to Cloudwatch Insights in the AWS web console`,
 	DisableFlagsInUseLine: true,
 	Run: func(_ *cobra.Command, _ []string) {
-		a, err := app.Init(AppName, UseAWSCredentials, MaxSessionDurationSeconds)
+		a, err := app.Init(logsAppName(), UseAWSCredentials, MaxSessionDurationSeconds)
 		checkErr(err)
 		checkErr(a.LoadSettings())
 		logGroupParam := strings.ReplaceAll(url.QueryEscape(a.Settings.LogGroup.Name), "%", "*")
@@ -193,14 +343,262 @@ var logsOpenCmd = &cobra.Command{
 	},
 }
 
+var (
+	logsQueryStart string
+	logsQueryEnd   string
+	logsQueryCSV   bool
+)
+
+// printQueryResults prints the results as an aligned table
+func printQueryResults(results *logs.QueryResults) error {
+	// minwidth, tabwidth, padding, padchar, flags
+	w := ansiterm.NewTabWriter(os.Stdout, 8, 8, 2, ' ', 0)
+
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		w.SetColorCapable(true)
+	}
+
+	w.SetStyle(ansiterm.Bold)
+	fmt.Fprintln(w, strings.Join(results.Fields, "\t"))
+	w.Reset()
+
+	for _, row := range results.Rows {
+		values := make([]string, 0, len(results.Fields))
+		for _, field := range results.Fields {
+			// tabs and newlines would break the table layout
+			values = append(values, strings.Join(strings.Fields(row[field]), " "))
+		}
+
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// printQueryResultsCSV prints the results as CSV with a header row
+func printQueryResultsCSV(results *logs.QueryResults) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(results.Fields); err != nil {
+		return err
+	}
+
+	for _, row := range results.Rows {
+		values := make([]string, 0, len(results.Fields))
+		for _, field := range results.Fields {
+			values = append(values, row[field])
+		}
+
+		if err := w.Write(values); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+// logsQueryCmd represents the logs query command
+var logsQueryCmd = &cobra.Command{
+	Use:   "query '<insights query>'",
+	Short: "run a CloudWatch Logs Insights query",
+	Long: `Run a CloudWatch Logs Insights query against the app's logs and print the results.
+
+See https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/CWL_QuerySyntax.html for the query syntax.
+For review apps, the results are limited to the review app's log streams.`,
+	Example: `apppack -a my-app logs query 'fields @timestamp, @message | filter @message like /ERROR/ | sort @timestamp desc | limit 20'
+apppack -a my-app logs query 'stats count(*) by bin(5m)' --start 1d --csv`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		start, err := TimeFromFlag(logsQueryStart)
+		checkErr(err)
+		end, err := TimeFromFlag(logsQueryEnd)
+		checkErr(err)
+		ui.StartSpinner()
+		a, err := app.Init(logsAppName(), UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		checkErr(a.LoadSettings())
+		query := logs.InsightsQuery{
+			Group: a.Settings.LogGroup.Name,
+			Query: args[0],
+			Start: start,
+			End:   end,
+		}
+		if a.IsReviewApp() {
+			query.StreamPrefix = fmt.Sprintf("pr%s-", *a.ReviewApp)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ui.Spinner.Suffix = " running query"
+		results, err := logs.RunQuery(ctx, cloudwatchlogs.NewFromConfig(a.Session), &query)
+		ui.Spinner.Stop()
+		ui.Spinner.Suffix = ""
+		checkErr(err)
+
+		switch {
+		case AsJSON:
+			checkErr(printJSON(results.Rows))
+		case logsQueryCSV:
+			checkErr(printQueryResultsCSV(results))
+		default:
+			if len(results.Rows) == 0 {
+				printWarning("no results")
+
+				return
+			}
+			checkErr(printQueryResults(results))
+			if results.Statistics != nil {
+				fmt.Println(aurora.Faint(fmt.Sprintf(
+					"%d records matched, %s scanned",
+					int64(results.Statistics.RecordsMatched),
+					humanize.Bytes(uint64(results.Statistics.BytesScanned)),
+				)))
+			}
+		}
+	},
+}
+
+var (
+	logsExportStart       string
+	logsExportEnd         string
+	logsExportPrefix      string
+	logsExportDir         string
+	logsExportConcurrency int
+)
+
+// logsExportCmd represents the logs export command
+var logsExportCmd = &cobra.Command{
+	Use:   "export -o <directory>",
+	Short: "download logs to local files",
+	Long: `Download the app's logs to a directory, fetching several log streams at once.
+
+Each log stream is written to a gzipped JSON Lines file in the streams/ subdirectory
+and all the events are combined, oldest first, in merged.jsonl.gz.
+
+Progress is recorded in export.json. If an export is interrupted, run the command
+again with the same directory to download the remaining streams. The resumed export
+keeps the original time range, even if --start or --stop are given.`,
+	Example: `apppack -a my-app logs export --start 6h -o incident-logs/
+apppack -a my-app logs export --start 2024-03-01T09:00:00Z --stop 2024-03-01T12:00:00Z --prefix web -o incident-logs/
+zcat incident-logs/merged.jsonl.gz | jq -r '.["@message"]'`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		manifest, err := logs.ReadExportManifest(logsExportDir)
+		checkErr(err)
+		query := logs.Query{Prefix: logsExportPrefix}
+		if manifest != nil {
+			// a relative --start would be different now, so continue with the original time range
+			query.Start = manifest.Start
+			query.End = manifest.End
+		} else {
+			query.Start, err = TimeFromFlag(logsExportStart)
+			checkErr(err)
+			query.End, err = TimeFromFlag(logsExportEnd)
+			checkErr(err)
+			if !query.End.After(query.Start) {
+				checkErr(errors.New("--stop must be after --start"))
+			}
+		}
+		ui.StartSpinner()
+		a, err := app.Init(logsAppName(), UseAWSCredentials, MaxSessionDurationSeconds)
+		checkErr(err)
+		checkErr(a.LoadSettings())
+		query.Group = a.Settings.LogGroup.Name
+		if a.IsReviewApp() {
+			query.Prefix = fmt.Sprintf("pr%s-%s", *a.ReviewApp, query.Prefix)
+		}
+		if manifest != nil {
+			remaining := len(manifest.Remaining())
+			ui.Spinner.Stop()
+			printWarning(fmt.Sprintf(
+				"resuming export of %s from %s to %s (%d of %d streams remaining)",
+				manifest.Group, manifest.Start.Local().Format(timeFmt), manifest.End.Local().Format(timeFmt),
+				remaining, len(manifest.Streams),
+			))
+			if flagIsSet(cmd.Flags(), "start") || flagIsSet(cmd.Flags(), "stop") {
+				printWarning("--start and --stop are ignored when resuming -- use a new directory to export a different time range")
+			}
+			ui.StartSpinner()
+		}
+		exporter := logs.NewExporter(a.Session, query, logsExportDir)
+		exporter.Concurrency = logsExportConcurrency
+		exporter.Progress = func(stream *logs.ExportedStream, done, total int) {
+			ui.Spinner.Suffix = fmt.Sprintf(" downloaded %d/%d streams (%s)", done, total, stream.Name)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ui.Spinner.Suffix = " listing log streams"
+		manifest, err = exporter.Run(ctx)
+		ui.Spinner.Stop()
+		ui.Spinner.Suffix = ""
+		if errors.Is(err, context.Canceled) {
+			printWarning("export interrupted -- run the same command again to resume")
+			os.Exit(1)
+		}
+		checkErr(err)
+		if len(manifest.Streams) == 0 {
+			printWarning(fmt.Sprintf("no log streams found in %s between %s and %s", query.Group, query.Start.Local().Format(timeFmt), query.End.Local().Format(timeFmt)))
+		}
+		printSuccess(fmt.Sprintf(
+			"exported %s events from %d streams to %s",
+			humanize.Comma(int64(manifest.Events())), len(manifest.Streams), logsExportDir,
+		))
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(logsCmd)
-	logsCmd.PersistentFlags().StringVarP(&AppName, "app-name", "a", "", "app name (required)")
+	logsCmd.PersistentFlags().StringArrayVarP(&logsAppNames, "app-name", "a", nil, "app name (required)\nCan be used multiple times to read the logs of several apps")
 	logsCmd.MarkPersistentFlagRequired("app-name")
 	logsCmd.PersistentFlags().BoolVar(&UseAWSCredentials, "aws-credentials", false, "use AWS credentials instead of AppPack.io federation")
 
 	logsCmd.AddCommand(logsOpenCmd)
-	logsCmd.Flags().StringVar(&sawConfig.Prefix, "prefix", "", `log group prefix filter
+
+	logsCmd.AddCommand(logsQueryCmd)
+	logsQueryCmd.Flags().StringVar(
+		&logsQueryStart,
+		"start",
+		"1h",
+		`query the logs from this point
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+	logsQueryCmd.Flags().StringVar(
+		&logsQueryEnd,
+		"stop",
+		"now",
+		`query the logs up to this point
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+	logsQueryCmd.Flags().BoolVar(&logsQueryCSV, "csv", false, "output as CSV")
+
+	logsCmd.AddCommand(logsExportCmd)
+	logsExportCmd.Flags().StringVar(
+		&logsExportStart,
+		"start",
+		"1h",
+		`export the logs from this point
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+	logsExportCmd.Flags().StringVar(
+		&logsExportEnd,
+		"stop",
+		"now",
+		`export the logs up to this point
+Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
+Valid time units are "s", "m", "h", "d".`,
+	)
+	logsExportCmd.Flags().StringVar(&logsExportPrefix, "prefix", "", `log stream prefix filter
+Use this to export logs for specific services, e.g. "web", "worker"`)
+	logsExportCmd.Flags().StringVarP(&logsExportDir, "output", "o", "", "directory to write the logs to (required)")
+	logsExportCmd.MarkFlagRequired("output")
+	logsExportCmd.Flags().IntVar(&logsExportConcurrency, "concurrency", logs.DefaultExportConcurrency, "number of log streams to download at once")
+
+	logsCmd.Flags().StringVar(&logsQuery.Prefix, "prefix", "", `log group prefix filter
 Use this to filter logs for specific services, e.g. "web", "worker"`)
 	logsCmd.Flags().StringVar(
 		&logsStart,
@@ -218,10 +616,21 @@ Valid time units are "s", "m", "h", "d".`,
 Takes an absolute timestamp in RFC3339 format, or a relative time (eg. 2h).
 Valid time units are "s", "m", "h", "d".`,
 	)
-	logsCmd.Flags().StringVar(&sawConfig.Filter, "filter", "", "event filter pattern")
-	logsCmd.Flags().BoolVar(&sawOutputConfig.Raw, "raw", false, "no timestamp, log group or colors")
-	logsCmd.Flags().BoolVar(&sawOutputConfig.Expand, "expand", false, "indent JSON log messages")
-	logsCmd.Flags().BoolVar(&sawOutputConfig.Invert, "invert", false, "invert colors for light terminal themes")
-	logsCmd.Flags().BoolVar(&sawOutputConfig.RawString, "rawString", false, "print JSON strings without escaping")
+	logsCmd.Flags().StringVar(&logsQuery.Filter, "filter", "", "event filter pattern")
+	logsCmd.Flags().BoolVar(&logsOutput.Raw, "raw", false, "no timestamp, log group or colors")
+	logsCmd.Flags().BoolVar(&logsOutput.Expand, "expand", false, "indent JSON log messages")
+	logsCmd.Flags().BoolVar(&logsOutput.Invert, "invert", false, "invert colors for light terminal themes")
+	logsCmd.Flags().BoolVar(&logsOutput.RawString, "rawString", false, "print JSON strings without escaping")
 	logsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Stream logs to console")
+	logsCmd.Flags().StringArrayVar(&logsWhere, "where", nil, `only show JSON log messages where the field matches, e.g. level=error or status>=500
+Operators are =, !=, >, >=, <, <=, and ~ (regular expression). Nested fields use dots, e.g. http.status.
+Can be used multiple times; all the conditions must match.`)
+	logsCmd.Flags().StringSliceVar(&logsOutput.Fields, "fields", nil, `only show these fields of JSON log messages, e.g. ts,level,msg
+@timestamp, @stream, and @message are available for every event`)
+	logsCmd.Flags().StringVarP(&logsOutputFormat, "output", "o", logsOutputText, "output format (text or jsonl)")
+	logsCmd.Flags().StringVar(&logsTask, "task", "", `only show the logs of this task (ID or ARN), including recently stopped tasks
+Unless --start is set, the logs start when the task started`)
+	logsCmd.Flags().StringVar(&logsProcess, "process", "", `only show the logs of this running process as named by "apppack ps", e.g. web.2
+Use --task for a process which has stopped. Unless --start is set, the logs start when the process started`)
+	logsCmd.MarkFlagsMutuallyExclusive("task", "process", "prefix")
 }
@@ -2,6 +2,7 @@ package cmd_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/apppackio/apppack/cmd"
 )
@@ -51,3 +52,31 @@ func TestLogFlagConversionForSaw(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeFromFlag(t *testing.T) {
+	t.Parallel()
+
+	before := time.Now()
+
+	val, err := cmd.TimeFromFlag("2h")
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if diff := before.Sub(val); diff < 2*time.Hour-time.Minute || diff > 2*time.Hour+time.Minute {
+		t.Errorf("Expected ~2h ago, got %s", val)
+	}
+
+	val, err = cmd.TimeFromFlag("2022-12-01T14:52:00Z")
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if !val.Equal(time.Date(2022, 12, 1, 14, 52, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2022-12-01T14:52:00Z, got %s", val)
+	}
+
+	if _, err = cmd.TimeFromFlag("2w"); err == nil {
+		t.Error("Expected error for 2w, got nil")
+	}
+}
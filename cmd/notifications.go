@@ -0,0 +1,117 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/apppackio/apppack/notify"
+	"github.com/logrusorgru/aurora"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+var (
+	notifyMethods []string
+	notifyWebhook string
+)
+
+// addNotifyFlags adds the flags which configure the notifications sent when a watched build or deploy finishes
+func addNotifyFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&notifyMethods, "notify", []string{}, "notify when the build or deploy finishes: desktop, bell or none (overrides the config file)")
+	flags.StringVar(&notifyWebhook, "notify-webhook", "", "URL to POST a JSON notification to when the build or deploy finishes (overrides the config file)")
+}
+
+// notificationConfig reads the --notify flags or, if they aren't set, the user's config file
+func notificationConfig() (*notify.Config, error) {
+	if len(notifyMethods) > 0 || notifyWebhook != "" {
+		return notify.ParseMethods(notifyMethods, notifyWebhook)
+	}
+
+	path, err := notify.ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return notify.LoadConfig(path)
+}
+
+// sendNotification notifies the user with the configured methods.
+// Problems are reported on stderr so they don't interrupt the command's output.
+func sendNotification(event *notify.Event) {
+	config, err := notificationConfig()
+	if err == nil && config.Enabled() {
+		err = notify.NewNotifier(config).Notify(context.Background(), event)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, aurora.Yellow("⚠  unable to send notification: "+err.Error()))
+	}
+}
+
+// displayAppName is the app name including the review app
+func displayAppName(a *app.App) string {
+	if a.IsReviewApp() {
+		return fmt.Sprintf("%s:%s", a.Name, *a.ReviewApp)
+	}
+
+	return a.Name
+}
+
+// notifyBuildFinished notifies the user of the result of watching the build.
+// The build status is read again so the commit and duration are current.
+func notifyBuildFinished(a *app.App, buildStatus *app.BuildStatus, err error) {
+	if refreshed, refreshErr := a.GetBuildStatus(buildStatus.BuildNumber); refreshErr == nil {
+		buildStatus = refreshed
+	} else {
+		logrus.WithFields(logrus.Fields{"error": refreshErr}).Debug("unable to refresh build status for notification")
+	}
+
+	result, _ := buildResult(err)
+	message := ""
+
+	if err != nil {
+		message = err.Error()
+	}
+
+	sendNotification(notify.NewEvent("build", displayAppName(a), buildStatus.BuildNumber, buildStatus.Commit, result, buildDuration(buildStatus, err), message))
+}
+
+// buildDuration is how long the build ran, up to the end of its last finished phase.
+// If the watch timed out or no phase has finished, it is the time since the build started.
+func buildDuration(buildStatus *app.BuildStatus, err error) time.Duration {
+	if buildStatus.Build.Start == 0 {
+		return 0
+	}
+
+	if !errors.Is(err, errBuildWatchTimeout) {
+		phase := buildStatus.FirstFailedPhase()
+		if phase == nil {
+			phase, _ = buildStatus.FinalPhase()
+		}
+
+		if phase != nil && phase.Phase.End > 0 {
+			return phase.Phase.EndTime().Sub(buildStatus.Build.StartTime())
+		}
+	}
+
+	return time.Since(buildStatus.Build.StartTime())
+}
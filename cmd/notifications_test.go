@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+)
+
+func TestBuildDuration(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now().Add(-48 * time.Hour)
+	phase := func(state string, end time.Time) app.BuildPhaseDetail {
+		return app.BuildPhaseDetail{State: state, Start: start.Unix(), End: end.Unix()}
+	}
+
+	finished := &app.BuildStatus{
+		Build:  phase(app.PhaseSuccess, start.Add(5*time.Minute)),
+		Deploy: phase(app.PhaseSuccess, start.Add(8*time.Minute)),
+	}
+	if d := buildDuration(finished, nil); d != 8*time.Minute {
+		t.Errorf("finished build: expected 8m, got %s", d)
+	}
+
+	failed := &app.BuildStatus{
+		Build: phase(app.PhaseSuccess, start.Add(5*time.Minute)),
+		Test:  phase(app.PhaseFailed, start.Add(7*time.Minute)),
+	}
+	if d := buildDuration(failed, &phaseFailedError{phase: failed.FirstFailedPhase()}); d != 7*time.Minute {
+		t.Errorf("failed build: expected 7m, got %s", d)
+	}
+
+	// a timed out watch reports how long it has been since the build started
+	if d := buildDuration(finished, fmt.Errorf("watching: %w", errBuildWatchTimeout)); d < 48*time.Hour {
+		t.Errorf("timed out build: expected at least 48h, got %s", d)
+	}
+}
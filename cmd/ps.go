@@ -193,15 +193,7 @@ var psCmd = &cobra.Command{
 			} else {
 				fmt.Printf("(%s)\n", aurora.Yellow(fmt.Sprintf("%d - %d", status.MinProcesses, status.MaxProcesses)))
 			}
-			sort.SliceStable(tasks, func(i, j int) bool {
-				if tasks[i].StartedAt == nil {
-					return false
-				} else if tasks[j].StartedAt == nil {
-					return true
-				}
-
-				return tasks[i].StartedAt.After(*tasks[j].StartedAt)
-			})
+			app.SortTasksNewestFirst(tasks)
 			for i, t := range tasks {
 				name := fmt.Sprintf("%s.%d", proc, i)
 				cpu, err := strconv.ParseFloat(*t.Cpu, 32)
@@ -146,7 +146,7 @@ var reviewappsCreateCmd = &cobra.Command{
 		ui.PrintSuccess("review app stack created")
 		ui.Spinner.Suffix = " triggering initial build..."
 		ui.StartSpinner()
-		build, err := a.StartBuild(true, "")
+		build, err := a.StartBuild(true, &app.BuildOptions{})
 		checkErr(err)
 		buildStatus, err := pollBuildStatus(a, int(*build.BuildNumber), 10)
 		checkErr(err)
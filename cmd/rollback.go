@@ -0,0 +1,151 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apppackio/apppack/app"
+	"github.com/apppackio/apppack/notify"
+	"github.com/apppackio/apppack/ui"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/logrusorgru/aurora"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// rollbackBuildCount is how many recent builds are searched for the previous successful build
+const rollbackBuildCount = 25
+
+var rollbackToBuild int
+
+// watchRollback prints service events until every service in the plan has finished deploying
+func watchRollback(a *app.App, plan []app.ServiceRollback, start time.Time) error {
+	services := make([]string, 0, len(plan))
+	taskDefinitions := map[string]string{}
+
+	for _, s := range plan {
+		services = append(services, s.Service)
+		taskDefinitions[s.Service] = s.TaskDefinition
+	}
+
+	seenEventIDs := map[string]bool{}
+	out := &buildOutput{}
+
+	for {
+		logrus.WithFields(logrus.Fields{"services": services}).Debug("polling service status")
+
+		described, err := a.DescribeServices(services)
+		if err != nil {
+			return err
+		}
+
+		out.printServiceEvents(described, start.Unix(), seenEventIDs)
+
+		done := true
+
+		for i := range described {
+			service := &described[i]
+
+			complete, err := app.RolloutComplete(service, taskDefinitions[aws.ToString(service.ServiceName)])
+			if err != nil {
+				return err
+			}
+
+			done = done && complete
+		}
+
+		if done {
+			return nil
+		}
+
+		ui.StartSpinner()
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "redeploy a previous successful build",
+	Long: `Redeploy the services of a previous successful build without rebuilding it.
+
+Defaults to the last successful build before the one currently deployed, skipping local builds. Use --to to choose the build.
+The release and postdeploy commands are not run. One-off tasks and shells keep using the latest build.
+The next deploy of the app (e.g. a new build or a stack update) replaces the rolled back services.`,
+	Example: `apppack -a my-app rollback
+apppack -a my-app rollback --to 41`,
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ui.StartSpinner()
+		a, err := app.Init(AppName, UseAWSCredentials, SessionDurationSeconds)
+		checkErr(err)
+		// the deploy status isn't updated by a rollback, so ask the services what they're running
+		current, err := a.DeployedBuildNumber()
+		checkErr(err)
+		var target *app.BuildStatus
+		if flagIsSet(cmd.Flags(), "to") {
+			target, err = a.GetBuildStatus(rollbackToBuild)
+			checkErr(err)
+			if target.Deploy.State != app.PhaseSuccess {
+				checkErr(fmt.Errorf("build #%d was not deployed successfully", target.BuildNumber))
+			}
+			if target.BuildNumber == current {
+				checkErr(fmt.Errorf("build #%d is already deployed", target.BuildNumber))
+			}
+			if target.Local {
+				ui.Spinner.Stop()
+				printWarning(fmt.Sprintf("build #%d is a local build and its source may not be in git", target.BuildNumber))
+				ui.StartSpinner()
+			}
+		} else {
+			builds, err := a.RecentBuilds(rollbackBuildCount)
+			checkErr(err)
+			target, err = app.PreviousSuccessfulBuild(builds, current)
+			checkErr(err)
+		}
+		plan, err := a.PlanRollback(target)
+		checkErr(err)
+		ui.Spinner.Stop()
+		fmt.Printf("Rolling back from build #%d to build #%d\n", current, target.BuildNumber)
+		printBuild(target)
+		printCommitLog(a.Session, target)
+		confirmAction(fmt.Sprintf("This will deploy build #%d to %d services.", target.BuildNumber, len(plan)), AppName)
+		start := time.Now()
+		ui.StartSpinner()
+		checkErr(a.Rollback(plan))
+		err = watchRollback(a, plan, start)
+		result, message := buildResultSuccess, ""
+		if err != nil {
+			result, message = buildResultDeployFailed, err.Error()
+		}
+		sendNotification(notify.NewEvent("rollback", displayAppName(a), target.BuildNumber, target.Commit, result, time.Since(start), message))
+		checkErr(err)
+		ui.Spinner.Stop()
+		printSuccess(fmt.Sprintf("build #%d (%s) is live", target.BuildNumber, aurora.Blue(target.Commit)))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.PersistentFlags().StringVarP(&AppName, "app-name", "a", "", "app name (required)")
+	rollbackCmd.MarkPersistentFlagRequired("app-name")
+	rollbackCmd.PersistentFlags().BoolVar(&UseAWSCredentials, "aws-credentials", false, "use AWS credentials instead of AppPack.io federation")
+	rollbackCmd.Flags().IntVar(&rollbackToBuild, "to", 0, "build number to roll back to")
+	addNotifyFlags(rollbackCmd.Flags())
+}
@@ -0,0 +1,569 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ExportManifestFile records the progress of an export so it can be resumed
+	ExportManifestFile = "export.json"
+	// ExportMergedFile contains the events of all the streams, oldest first
+	ExportMergedFile = "merged.jsonl.gz"
+	// exportStreamsDir is the directory with one file per stream
+	exportStreamsDir = "streams"
+	// DefaultExportConcurrency is the number of streams downloaded at once
+	DefaultExportConcurrency = 4
+	// maxMergeFiles is the most files opened at once while merging, larger exports are merged in passes
+	maxMergeFiles = 64
+)
+
+// ExportedStream is the progress of a single stream in an export
+type ExportedStream struct {
+	Name     string `json:"name"`
+	File     string `json:"file"`
+	Events   int    `json:"events"`
+	Complete bool   `json:"complete"`
+}
+
+// ExportManifest describes an export and which streams have been downloaded
+type ExportManifest struct {
+	Group   string                     `json:"group"`
+	Prefix  string                     `json:"prefix"`
+	Start   time.Time                  `json:"start"`
+	End     time.Time                  `json:"end"`
+	Streams map[string]*ExportedStream `json:"streams"`
+}
+
+// Events is the total number of events downloaded
+func (m *ExportManifest) Events() int {
+	total := 0
+	for _, s := range m.Streams {
+		total += s.Events
+	}
+
+	return total
+}
+
+// Remaining returns the names of the streams which have not been downloaded yet, sorted
+func (m *ExportManifest) Remaining() []string {
+	var names []string
+
+	for name, s := range m.Streams {
+		if !s.Complete {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// ReadExportManifest reads the manifest of an export in dir.
+// It returns nil without an error if the directory doesn't contain an export.
+func ReadExportManifest(dir string) (*ExportManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ExportManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := ExportManifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", ExportManifestFile, err)
+	}
+
+	if manifest.Streams == nil {
+		manifest.Streams = map[string]*ExportedStream{}
+	}
+
+	return &manifest, nil
+}
+
+// exportLine is a single event in an export file
+type exportLine struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Stream    string    `json:"@stream"`
+	Message   string    `json:"@message"`
+}
+
+// Exporter downloads the streams of a log group to gzipped JSON Lines files
+type Exporter struct {
+	api   API
+	query Query
+	dir   string
+	sleep sleepFunc
+	// Concurrency is the number of streams downloaded at once
+	Concurrency int
+	// Progress is called after each stream is downloaded
+	Progress func(stream *ExportedStream, done, total int)
+
+	mu       sync.Mutex
+	manifest *ExportManifest
+}
+
+// NewExporter creates an Exporter for the query's group, prefix, and time range
+// with a CloudWatch Logs client from the AWS config. Streams and Filter are ignored.
+func NewExporter(cfg aws.Config, query Query, dir string) *Exporter {
+	return NewExporterWithAPI(cloudwatchlogs.NewFromConfig(cfg), query, dir)
+}
+
+// NewExporterWithAPI creates an Exporter using the provided client
+func NewExporterWithAPI(api API, query Query, dir string) *Exporter {
+	return &Exporter{api: api, query: query, dir: dir, sleep: sleep, Concurrency: DefaultExportConcurrency}
+}
+
+// streamFile is the path of the stream's file relative to the export directory.
+// A short hash of the name keeps streams which differ only in replaced characters apart.
+func streamFile(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+	sum := sha256.Sum256([]byte(name))
+
+	return filepath.Join(exportStreamsDir, fmt.Sprintf("%s-%x.jsonl.gz", replacer.Replace(name), sum[:4]))
+}
+
+// ListStreams returns the names of the streams with events between the query start and end times
+func (e *Exporter) ListStreams(ctx context.Context) ([]string, error) {
+	input := &cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: &e.query.Group}
+	if e.query.Prefix != "" {
+		input.LogStreamNamePrefix = &e.query.Prefix
+	}
+
+	var names []string
+
+	for {
+		var page *cloudwatchlogs.DescribeLogStreamsOutput
+
+		err := retry(ctx, e.sleep, maxThrottledRetries, func() error {
+			var err error
+			page, err = e.api.DescribeLogStreams(ctx, input)
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range page.LogStreams {
+			if s.FirstEventTimestamp == nil {
+				continue
+			}
+
+			first := time.UnixMilli(aws.ToInt64(s.FirstEventTimestamp))
+			// LastEventTimestamp can lag behind by up to an hour, so use whichever is later
+			last := time.UnixMilli(max(aws.ToInt64(s.LastEventTimestamp), aws.ToInt64(s.LastIngestionTime)))
+
+			if last.Before(e.query.Start) || (!e.query.End.IsZero() && first.After(e.query.End)) {
+				continue
+			}
+
+			names = append(names, aws.ToString(s.LogStreamName))
+		}
+
+		if page.NextToken == nil || aws.ToString(page.NextToken) == aws.ToString(input.NextToken) {
+			break
+		}
+
+		input.NextToken = page.NextToken
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// loadManifest reads the manifest from a previous run, checking it is for the same export
+func (e *Exporter) loadManifest() (*ExportManifest, error) {
+	manifest, err := ReadExportManifest(e.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		return &ExportManifest{
+			Group:   e.query.Group,
+			Prefix:  e.query.Prefix,
+			Start:   e.query.Start,
+			End:     e.query.End,
+			Streams: map[string]*ExportedStream{},
+		}, nil
+	}
+
+	if manifest.Group != e.query.Group || manifest.Prefix != e.query.Prefix ||
+		!manifest.Start.Equal(e.query.Start) || !manifest.End.Equal(e.query.End) {
+		return nil, fmt.Errorf("%s already contains a different export -- use another directory", e.dir)
+	}
+
+	return manifest, nil
+}
+
+// saveManifest writes the manifest, replacing the previous one atomically
+func (e *Exporter) saveManifest() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	data, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(e.dir, ExportManifestFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Run downloads the streams which haven't been downloaded by a previous run,
+// then writes the merged file. Streams are downloaded completely or not at all,
+// so an interrupted export can be continued by running it again with the same query.
+func (e *Exporter) Run(ctx context.Context) (*ExportManifest, error) {
+	if e.query.End.IsZero() {
+		return nil, errors.New("an export requires an end time")
+	}
+
+	if err := os.MkdirAll(filepath.Join(e.dir, exportStreamsDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	manifest, err := e.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	e.manifest = manifest
+
+	names, err := e.ListStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if _, ok := manifest.Streams[name]; !ok {
+			manifest.Streams[name] = &ExportedStream{Name: name, File: streamFile(name)}
+		}
+	}
+
+	if err := e.saveManifest(); err != nil {
+		return nil, err
+	}
+
+	if err := e.downloadAll(ctx, manifest.Remaining()); err != nil {
+		return nil, err
+	}
+
+	if err := e.merge(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// downloadAll downloads the streams concurrently, stopping at the first error
+func (e *Exporter) downloadAll(ctx context.Context, names []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan string)
+	errs := make(chan error, len(names))
+	total := len(e.manifest.Streams)
+	done := total - len(names)
+
+	var wg sync.WaitGroup
+
+	for range max(e.Concurrency, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for name := range queue {
+				if err := e.download(ctx, e.manifest.Streams[name]); err != nil {
+					errs <- fmt.Errorf("unable to export %s: %w", name, err)
+
+					cancel()
+
+					return
+				}
+
+				if err := e.saveManifest(); err != nil {
+					errs <- err
+
+					cancel()
+
+					return
+				}
+
+				if e.Progress != nil {
+					e.mu.Lock()
+					done++
+					e.Progress(e.manifest.Streams[name], done, total)
+					e.mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, name := range names {
+		select {
+		case queue <- name:
+		case <-ctx.Done():
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// download writes all the events of the stream to its file, oldest first.
+// FilterLogEvents returns the events of a single stream in order, so they are written as they arrive.
+func (e *Exporter) download(ctx context.Context, stream *ExportedStream) error {
+	query := e.query
+	query.Prefix = ""
+	query.Filter = ""
+	query.Streams = []string{stream.Name}
+
+	events := 0
+	reader := Reader{api: e.api, query: query, sleep: e.sleep}
+
+	err := writeExportFile(filepath.Join(e.dir, stream.File), func(write func(*exportLine) error) error {
+		return reader.Events(ctx, func(event *Event) error {
+			events++
+
+			return write(&exportLine{Timestamp: event.Timestamp, Stream: event.Stream, Message: event.Message})
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"stream": stream.Name, "events": events}).Debug("exported log stream")
+
+	e.mu.Lock()
+	stream.Events = events
+	stream.Complete = true
+	e.mu.Unlock()
+
+	return nil
+}
+
+// writeExportFile writes a gzipped JSON Lines file with the lines from fill.
+// The file is written to a temporary path first so an interrupted write never leaves a partial file.
+func writeExportFile(path string, fill func(write func(*exportLine) error) error) error {
+	tmp := path + ".partial"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	buf := bufio.NewWriter(gz)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := fill(func(line *exportLine) error { return encoder.Encode(line) }); err != nil {
+		return err
+	}
+
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// merge writes the events of all the streams to the merged file, oldest first
+func (e *Exporter) merge() error {
+	var files []string
+
+	for _, name := range slices.Sorted(maps.Keys(e.manifest.Streams)) {
+		files = append(files, filepath.Join(e.dir, e.manifest.Streams[name].File))
+	}
+
+	// merge in passes so the number of open files stays below the OS limit
+	var intermediate []string
+
+	defer func() {
+		for _, f := range intermediate {
+			_ = os.Remove(f)
+		}
+	}()
+
+	for pass := 0; len(files) > maxMergeFiles; pass++ {
+		var next []string
+
+		for i := 0; i < len(files); i += maxMergeFiles {
+			path := filepath.Join(e.dir, fmt.Sprintf("merge-%d-%d.jsonl.gz", pass, i/maxMergeFiles))
+			if err := mergeFiles(path, files[i:min(i+maxMergeFiles, len(files))]); err != nil {
+				return err
+			}
+
+			intermediate = append(intermediate, path)
+			next = append(next, path)
+		}
+
+		files = next
+	}
+
+	return mergeFiles(filepath.Join(e.dir, ExportMergedFile), files)
+}
+
+// exportFileReader reads the lines of an export file in order
+type exportFileReader struct {
+	file    *os.File
+	gz      *gzip.Reader
+	decoder *json.Decoder
+	line    exportLine
+}
+
+func openExportFile(path string) (*exportFileReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	return &exportFileReader{file: f, gz: gz, decoder: json.NewDecoder(bufio.NewReader(gz))}, nil
+}
+
+// next reads the next line, returning false at the end of the file
+func (r *exportFileReader) next() (bool, error) {
+	r.line = exportLine{}
+
+	err := r.decoder.Decode(&r.line)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (r *exportFileReader) Close() error {
+	r.gz.Close()
+
+	return r.file.Close()
+}
+
+// exportHeap orders the readers by the timestamp of their current line
+type exportHeap []*exportFileReader
+
+func (h exportHeap) Len() int { return len(h) }
+
+func (h exportHeap) Less(i, j int) bool { return h[i].line.Timestamp.Before(h[j].line.Timestamp) }
+
+func (h exportHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *exportHeap) Push(x any) { *h = append(*h, x.(*exportFileReader)) }
+
+func (h *exportHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return r
+}
+
+// mergeFiles writes the lines of the sorted source files to dst, oldest first
+func mergeFiles(dst string, srcs []string) error {
+	readers := exportHeap{}
+
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	for _, src := range srcs {
+		r, err := openExportFile(src)
+		if err != nil {
+			return err
+		}
+
+		ok, err := r.next()
+		if err != nil {
+			r.Close()
+
+			return fmt.Errorf("unable to read %s: %w", src, err)
+		}
+
+		if !ok {
+			r.Close()
+
+			continue
+		}
+
+		readers = append(readers, r)
+	}
+
+	heap.Init(&readers)
+
+	return writeExportFile(dst, func(write func(*exportLine) error) error {
+		for readers.Len() > 0 {
+			r := readers[0]
+			if err := write(&r.line); err != nil {
+				return err
+			}
+
+			ok, err := r.next()
+			if err != nil {
+				return fmt.Errorf("unable to read %s: %w", r.file.Name(), err)
+			}
+
+			if ok {
+				heap.Fix(&readers, 0)
+			} else {
+				heap.Pop(&readers)
+				r.Close()
+			}
+		}
+
+		return nil
+	})
+}
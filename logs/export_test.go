@@ -0,0 +1,282 @@
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// fakeExportAPI serves a fixed set of streams and records which streams were downloaded
+type fakeExportAPI struct {
+	mu         sync.Mutex
+	streams    []types.LogStream
+	events     map[string][]types.FilteredLogEvent
+	fail       map[string]error
+	downloaded []string
+}
+
+func (f *fakeExportAPI) DescribeLogStreams(_ context.Context, input *cloudwatchlogs.DescribeLogStreamsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	// serve one stream per page to exercise pagination
+	index := 0
+	if input.NextToken != nil {
+		index = len(aws.ToString(input.NextToken))
+	}
+
+	out := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	if index < len(f.streams) {
+		out.LogStreams = f.streams[index : index+1]
+	}
+
+	if index+1 < len(f.streams) {
+		out.NextToken = aws.String(strings.Repeat("x", index+1))
+	}
+
+	return out, nil
+}
+
+func (f *fakeExportAPI) FilterLogEvents(_ context.Context, input *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	name := input.LogStreamNames[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.downloaded = append(f.downloaded, name)
+
+	if err := f.fail[name]; err != nil {
+		return nil, err
+	}
+
+	return &cloudwatchlogs.FilterLogEventsOutput{Events: f.events[name]}, nil
+}
+
+func logStream(name string, first, last int64) types.LogStream {
+	return types.LogStream{
+		LogStreamName:       aws.String(name),
+		FirstEventTimestamp: aws.Int64(first),
+		LastEventTimestamp:  aws.Int64(last),
+	}
+}
+
+func streamEvent(stream string, ms int64, message string) types.FilteredLogEvent {
+	return types.FilteredLogEvent{
+		EventId:       aws.String(stream + message),
+		Timestamp:     aws.Int64(ms),
+		LogStreamName: aws.String(stream),
+		Message:       aws.String(message),
+	}
+}
+
+func newFakeExportAPI() *fakeExportAPI {
+	return &fakeExportAPI{
+		streams: []types.LogStream{
+			logStream("web/web/a", 1000, 5000),
+			logStream("web/web/b", 2000, 6000),
+			// outside the time range
+			logStream("web/web/old", 10, 20),
+			// no events
+			{LogStreamName: aws.String("web/web/empty")},
+		},
+		events: map[string][]types.FilteredLogEvent{
+			"web/web/a": {streamEvent("web/web/a", 1000, "a1"), streamEvent("web/web/a", 4000, "a2")},
+			"web/web/b": {streamEvent("web/web/b", 2000, "b1"), streamEvent("web/web/b", 3000, "b2")},
+		},
+		fail: map[string]error{},
+	}
+}
+
+func readExport(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+
+	decoder := json.NewDecoder(gz)
+	for decoder.More() {
+		line := exportLine{}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, line.Message)
+	}
+
+	return messages
+}
+
+func TestStreamFile(t *testing.T) {
+	t.Parallel()
+
+	if streamFile("web/web/a") == streamFile("web_web/a") {
+		t.Errorf("expected different files for streams which differ only in replaced characters, got %s", streamFile("web/web/a"))
+	}
+
+	if dir := filepath.Dir(streamFile("web/web/a")); dir != exportStreamsDir {
+		t.Errorf("expected the file in %s, got %s", exportStreamsDir, dir)
+	}
+}
+
+func TestExporterRun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	api := newFakeExportAPI()
+	query := Query{Group: "group", Prefix: "web", Start: time.UnixMilli(500), End: time.UnixMilli(9000)}
+	exporter := NewExporterWithAPI(api, query, dir)
+
+	var progress []int
+
+	exporter.Progress = func(_ *ExportedStream, done, total int) {
+		progress = append(progress, done*10+total)
+	}
+
+	manifest, err := exporter.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Streams) != 2 || manifest.Events() != 4 {
+		t.Errorf("expected 4 events from 2 streams, got %d from %d", manifest.Events(), len(manifest.Streams))
+	}
+
+	if len(progress) != 2 || progress[1] != 22 {
+		t.Errorf("expected progress 1/2 then 2/2, got %v", progress)
+	}
+
+	if messages := readExport(t, filepath.Join(dir, streamFile("web/web/b"))); !reflect.DeepEqual(messages, []string{"b1", "b2"}) {
+		t.Errorf("expected stream in order, got %v", messages)
+	}
+
+	if messages := readExport(t, filepath.Join(dir, ExportMergedFile)); !reflect.DeepEqual(messages, []string{"a1", "b1", "b2", "a2"}) {
+		t.Errorf("expected merged events sorted by time, got %v", messages)
+	}
+
+	saved, err := ReadExportManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.Group != "group" || len(saved.Remaining()) != 0 || !saved.Start.Equal(query.Start) {
+		t.Errorf("unexpected manifest %+v", saved)
+	}
+}
+
+func TestExporterResume(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	api := newFakeExportAPI()
+	errDenied := errors.New("access denied")
+	api.fail["web/web/b"] = errDenied
+	query := Query{Group: "group", Start: time.UnixMilli(500), End: time.UnixMilli(9000)}
+
+	exporter := NewExporterWithAPI(api, query, dir)
+	exporter.Concurrency = 1
+
+	if _, err := exporter.Run(context.Background()); !errors.Is(err, errDenied) {
+		t.Fatalf("expected %s, got %v", errDenied, err)
+	}
+
+	saved, err := ReadExportManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := saved.Remaining(); !reflect.DeepEqual(remaining, []string{"web/web/b"}) {
+		t.Errorf("expected web/web/b remaining, got %v", remaining)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ExportMergedFile)); err == nil {
+		t.Error("expected no merged file for an incomplete export")
+	}
+
+	delete(api.fail, "web/web/b")
+	api.downloaded = nil
+
+	manifest, err := NewExporterWithAPI(api, query, dir).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(api.downloaded, []string{"web/web/b"}) {
+		t.Errorf("expected only web/web/b to be downloaded, got %v", api.downloaded)
+	}
+
+	if manifest.Events() != 4 {
+		t.Errorf("expected 4 events, got %d", manifest.Events())
+	}
+
+	if messages := readExport(t, filepath.Join(dir, ExportMergedFile)); len(messages) != 4 {
+		t.Errorf("expected 4 merged events, got %v", messages)
+	}
+
+	other := query
+	other.Prefix = "worker"
+
+	if _, err := NewExporterWithAPI(api, other, dir).Run(context.Background()); err == nil {
+		t.Error("expected an error exporting a different query to the same directory")
+	}
+}
+
+func TestMergeFilesInPasses(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	api := &fakeExportAPI{events: map[string][]types.FilteredLogEvent{}, fail: map[string]error{}}
+
+	// more streams than can be merged at once, with interleaved events
+	for i := range maxMergeFiles + 6 {
+		name := "web/web/" + strings.Repeat("s", i+1)
+		api.streams = append(api.streams, logStream(name, 1000, 9000))
+		api.events[name] = []types.FilteredLogEvent{
+			streamEvent(name, int64(1000+i), "first"),
+			streamEvent(name, int64(5000+i), "second"),
+		}
+	}
+
+	query := Query{Group: "group", Start: time.UnixMilli(500), End: time.UnixMilli(9000)}
+	if _, err := NewExporterWithAPI(api, query, dir).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readExport(t, filepath.Join(dir, ExportMergedFile))
+	if len(messages) != 2*len(api.streams) {
+		t.Fatalf("expected %d events, got %d", 2*len(api.streams), len(messages))
+	}
+
+	for i, message := range messages {
+		expected := "first"
+		if i >= len(api.streams) {
+			expected = "second"
+		}
+
+		if message != expected {
+			t.Fatalf("expected %s at %d, got %s", expected, i, message)
+		}
+	}
+
+	if leftover, _ := filepath.Glob(filepath.Join(dir, "merge-*")); len(leftover) > 0 {
+		t.Errorf("expected intermediate files to be removed, got %v", leftover)
+	}
+}
@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TylerBrock/colorjson"
+	"github.com/fatih/color"
+)
+
+// Output controls how events are formatted
+type Output struct {
+	// Raw prints only the message, without timestamp, stream, or colors
+	Raw bool
+	// Expand indents JSON messages
+	Expand bool
+	// Invert uses colors suited to light terminal themes
+	Invert bool
+	// RawString prints JSON strings without escaping
+	RawString bool
+	// Where skips events with JSON messages which don't match all the conditions
+	Where []*Condition
+	// Fields are printed instead of the whole JSON message
+	Fields []string
+	// JSONLines prints each event as a JSON object on a single line
+	JSONLines bool
+	// Apps are the labels of the apps being read, in the order their colors are assigned
+	Apps      []string
+	formatter *colorjson.Formatter
+}
+
+// appColors are used in turn for the app labels
+var appColors = []color.Attribute{color.FgCyan, color.FgMagenta, color.FgGreen, color.FgYellow, color.FgBlue}
+
+func (o *Output) jsonFormatter() *colorjson.Formatter {
+	if o.formatter != nil {
+		return o.formatter
+	}
+
+	o.formatter = colorjson.NewFormatter()
+
+	if o.Expand {
+		o.formatter.Indent = 4
+	}
+
+	if o.RawString {
+		o.formatter.RawStrings = true
+	}
+
+	if o.Invert {
+		o.formatter.KeyColor = color.New(color.FgBlack)
+	}
+
+	return o.formatter
+}
+
+// Format returns the event as a line of text for the console
+func (o *Output) Format(event *Event) string {
+	message := strings.TrimRight(event.Message, "\n")
+	if o.Raw {
+		return message
+	}
+
+	prefix := o.prefix(event)
+
+	parsed := map[string]any{}
+	if err := json.Unmarshal([]byte(message), &parsed); err != nil {
+		return fmt.Sprintf("%s %s", prefix, message)
+	}
+
+	formatted, err := o.jsonFormatter().Marshal(parsed)
+	if err != nil {
+		return fmt.Sprintf("%s %s", prefix, message)
+	}
+
+	return fmt.Sprintf("%s %s", prefix, formatted)
+}
+
+// prefix returns the timestamp and stream of the event
+func (o *Output) prefix(event *Event) string {
+	red := color.New(color.FgRed).SprintFunc()
+	white := color.New(color.FgWhite).SprintFunc()
+
+	if event.App == "" {
+		return fmt.Sprintf("[%s] (%s)", red(event.Timestamp.Format(time.RFC3339)), white(event.Stream))
+	}
+
+	appColor := color.New(appColors[max(slices.Index(o.Apps, event.App), 0)%len(appColors)], color.Bold).SprintFunc()
+
+	return fmt.Sprintf("[%s] %s (%s)", red(event.Timestamp.Format(time.RFC3339)), appColor(event.App), white(event.Stream))
+}
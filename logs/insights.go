@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sirupsen/logrus"
+)
+
+// InsightsAPI is the subset of the CloudWatch Logs client used to run Logs Insights queries
+type InsightsAPI interface {
+	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+}
+
+// InsightsQuery is a CloudWatch Logs Insights query
+type InsightsQuery struct {
+	Group string
+	Query string
+	// StreamPrefix limits the results to streams with names starting with the prefix
+	StreamPrefix string
+	Start        time.Time
+	End          time.Time
+}
+
+// QueryString returns the query with a filter for the stream prefix prepended
+func (q *InsightsQuery) QueryString() string {
+	if q.StreamPrefix == "" {
+		return q.Query
+	}
+
+	return fmt.Sprintf("filter @logStream like /^%s/\n| %s", q.StreamPrefix, q.Query)
+}
+
+// QueryResults are the rows returned by an InsightsQuery
+type QueryResults struct {
+	// Fields are the names of the columns in the order they first appear
+	Fields []string
+	Rows   []map[string]string
+	// Statistics summarize the data scanned by the query
+	Statistics *types.QueryStatistics
+}
+
+// NewQueryResults converts the results of GetQueryResults, omitting the internal @ptr field
+func NewQueryResults(results [][]types.ResultField, stats *types.QueryStatistics) *QueryResults {
+	queryResults := QueryResults{Rows: []map[string]string{}, Statistics: stats}
+	seen := map[string]bool{}
+
+	for _, result := range results {
+		row := map[string]string{}
+
+		for _, field := range result {
+			name := aws.ToString(field.Field)
+			if name == "@ptr" {
+				continue
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				queryResults.Fields = append(queryResults.Fields, name)
+			}
+
+			row[name] = aws.ToString(field.Value)
+		}
+
+		queryResults.Rows = append(queryResults.Rows, row)
+	}
+
+	return &queryResults
+}
+
+// RunQuery starts the query and waits for the results.
+// The query is stopped if the context is cancelled before it completes.
+func RunQuery(ctx context.Context, api InsightsAPI, query *InsightsQuery) (*QueryResults, error) {
+	return runQuery(ctx, api, query, sleep)
+}
+
+func runQuery(ctx context.Context, api InsightsAPI, query *InsightsQuery, sleep sleepFunc) (*QueryResults, error) {
+	var started *cloudwatchlogs.StartQueryOutput
+
+	err := retry(ctx, sleep, maxThrottledRetries, func() error {
+		var err error
+		started, err = api.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+			LogGroupName: &query.Group,
+			QueryString:  aws.String(query.QueryString()),
+			StartTime:    aws.Int64(query.Start.Unix()),
+			EndTime:      aws.Int64(query.End.Unix()),
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{"query_id": aws.ToString(started.QueryId)}).Debug("started Logs Insights query")
+
+	for {
+		if err := sleep(ctx, PollInterval); err != nil {
+			// use a new context because the query context is done
+			_, _ = api.StopQuery(context.Background(), &cloudwatchlogs.StopQueryInput{QueryId: started.QueryId})
+
+			return nil, err
+		}
+
+		var resp *cloudwatchlogs.GetQueryResultsOutput
+
+		err := retry(ctx, sleep, maxThrottledRetries, func() error {
+			var err error
+			resp, err = api.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{QueryId: started.QueryId})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.Status {
+		case types.QueryStatusComplete:
+			return NewQueryResults(resp.Results, resp.Statistics), nil
+		case types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout:
+			return nil, fmt.Errorf("query %s", strings.ToLower(string(resp.Status)))
+		}
+	}
+}
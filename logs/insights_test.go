@@ -0,0 +1,104 @@
+package logs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// fakeInsightsAPI returns the queued statuses from GetQueryResults in order
+type fakeInsightsAPI struct {
+	statuses []types.QueryStatus
+	started  *cloudwatchlogs.StartQueryInput
+	stopped  bool
+}
+
+func (f *fakeInsightsAPI) StartQuery(_ context.Context, input *cloudwatchlogs.StartQueryInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	f.started = input
+
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("query-id")}, nil
+}
+
+func (f *fakeInsightsAPI) GetQueryResults(_ context.Context, _ *cloudwatchlogs.GetQueryResultsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	status := f.statuses[0]
+	f.statuses = f.statuses[1:]
+
+	return &cloudwatchlogs.GetQueryResultsOutput{
+		Status: status,
+		Results: [][]types.ResultField{
+			{{Field: aws.String("@timestamp"), Value: aws.String("2024-03-01 12:00:00.000")}, {Field: aws.String("@ptr"), Value: aws.String("abc")}},
+			{{Field: aws.String("@timestamp"), Value: aws.String("2024-03-01 12:00:01.000")}, {Field: aws.String("@message"), Value: aws.String("hello")}},
+		},
+	}, nil
+}
+
+func (f *fakeInsightsAPI) StopQuery(_ context.Context, _ *cloudwatchlogs.StopQueryInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+	f.stopped = true
+
+	return &cloudwatchlogs.StopQueryOutput{}, nil
+}
+
+func noSleep(context.Context, time.Duration) error {
+	return nil
+}
+
+func TestRunQuery(t *testing.T) {
+	t.Parallel()
+
+	api := &fakeInsightsAPI{statuses: []types.QueryStatus{types.QueryStatusScheduled, types.QueryStatusRunning, types.QueryStatusComplete}}
+	query := InsightsQuery{
+		Group:        "group",
+		Query:        "fields @timestamp, @message",
+		StreamPrefix: "pr12-",
+		Start:        time.Unix(1000, 0),
+		End:          time.Unix(2000, 0),
+	}
+
+	results, err := runQuery(context.Background(), api, &query, noSleep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "filter @logStream like /^pr12-/\n| fields @timestamp, @message"; aws.ToString(api.started.QueryString) != expected {
+		t.Errorf("expected %q, got %q", expected, aws.ToString(api.started.QueryString))
+	}
+
+	if aws.ToInt64(api.started.StartTime) != 1000 || aws.ToInt64(api.started.EndTime) != 2000 {
+		t.Errorf("expected start 1000 and end 2000, got %d and %d", aws.ToInt64(api.started.StartTime), aws.ToInt64(api.started.EndTime))
+	}
+
+	if !reflect.DeepEqual(results.Fields, []string{"@timestamp", "@message"}) {
+		t.Errorf("expected [@timestamp @message], got %v", results.Fields)
+	}
+
+	expectedRows := []map[string]string{
+		{"@timestamp": "2024-03-01 12:00:00.000"},
+		{"@timestamp": "2024-03-01 12:00:01.000", "@message": "hello"},
+	}
+	if !reflect.DeepEqual(results.Rows, expectedRows) {
+		t.Errorf("expected %v, got %v", expectedRows, results.Rows)
+	}
+}
+
+func TestRunQueryFailed(t *testing.T) {
+	t.Parallel()
+
+	api := &fakeInsightsAPI{statuses: []types.QueryStatus{types.QueryStatusRunning, types.QueryStatusFailed}}
+
+	if _, err := runQuery(context.Background(), api, &InsightsQuery{Group: "group", Query: "fields @message"}, noSleep); err == nil {
+		t.Error("expected error for failed query, got nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	api = &fakeInsightsAPI{}
+	if _, err := RunQuery(ctx, api, &InsightsQuery{Group: "group", Query: "fields @message"}); err == nil || !api.stopped {
+		t.Errorf("expected cancelled query to be stopped, got %v", err)
+	}
+}
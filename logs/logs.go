@@ -0,0 +1,291 @@
+// Package logs reads events from CloudWatch Logs with the same semantics as
+// the `--start`, `--stop`, `--prefix`, `--filter`, and `--follow` flags of `apppack logs`.
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PollInterval is how often new events are requested when following
+	PollInterval = time.Second
+	// followLookback is how far before the newest event each poll starts,
+	// so events which are ingested late are still shown
+	followLookback = 10 * time.Second
+	// maxBackoff is the longest wait between retries of a throttled request
+	maxBackoff = 30 * time.Second
+	// maxThrottledRetries is how many times a throttled request is retried when not following
+	maxThrottledRetries = 8
+	// maxStreamNames is the most stream names FilterLogEvents accepts
+	maxStreamNames = 100
+)
+
+// API is the subset of the CloudWatch Logs client used to read events
+type API interface {
+	cloudwatchlogs.DescribeLogStreamsAPIClient
+	cloudwatchlogs.FilterLogEventsAPIClient
+}
+
+// Query describes the events to read
+type Query struct {
+	// Group is the name of the log group
+	Group string
+	// Prefix limits the events to streams with names starting with the prefix
+	Prefix string
+	// Streams limits the events to the named streams and takes precedence over Prefix
+	Streams []string
+	// Filter is a CloudWatch Logs filter pattern
+	Filter string
+	// Start is the time of the oldest event, defaults to now
+	Start time.Time
+	// End is the time of the newest event, the zero value means there is no end
+	End time.Time
+	// App labels the events when reading the logs of several apps at once
+	App string
+}
+
+// Event is a single log event
+type Event struct {
+	ID        string
+	Timestamp time.Time
+	Stream    string
+	Message   string
+	// App is the label of the Query the event was read by
+	App string
+}
+
+// Reader reads log events from CloudWatch Logs
+type Reader struct {
+	api   API
+	query Query
+	sleep sleepFunc
+}
+
+// sleepFunc waits between polls and retries, it is replaced in tests
+type sleepFunc func(ctx context.Context, d time.Duration) error
+
+// NewReader creates a Reader for the query with a CloudWatch Logs client from the AWS config
+func NewReader(cfg aws.Config, query Query) *Reader {
+	return NewReaderWithAPI(cloudwatchlogs.NewFromConfig(cfg), query)
+}
+
+// NewReaderWithAPI creates a Reader for the query using the provided client
+func NewReaderWithAPI(api API, query Query) *Reader {
+	if query.Start.IsZero() {
+		query.Start = time.Now()
+	}
+
+	return &Reader{api: api, query: query, sleep: sleep}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// HasStreams reports whether any streams in the group match the query prefix
+func (r *Reader) HasStreams(ctx context.Context) (bool, error) {
+	input := &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: &r.query.Group,
+		Limit:        aws.Int32(1),
+	}
+	if r.query.Prefix != "" {
+		input.LogStreamNamePrefix = &r.query.Prefix
+	}
+
+	var resp *cloudwatchlogs.DescribeLogStreamsOutput
+
+	err := retry(ctx, r.sleep, maxThrottledRetries, func() error {
+		var err error
+		resp, err = r.api.DescribeLogStreams(ctx, input)
+
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(resp.LogStreams) > 0, nil
+}
+
+func (r *Reader) filterInput(start time.Time) (*cloudwatchlogs.FilterLogEventsInput, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: &r.query.Group,
+		StartTime:    aws.Int64(start.UnixMilli()),
+	}
+
+	if len(r.query.Streams) > maxStreamNames {
+		return nil, fmt.Errorf("too many log streams (%d) -- at most %d can be read at once", len(r.query.Streams), maxStreamNames)
+	}
+
+	if len(r.query.Streams) > 0 {
+		input.LogStreamNames = r.query.Streams
+	} else if r.query.Prefix != "" {
+		input.LogStreamNamePrefix = &r.query.Prefix
+	}
+
+	if !r.query.End.IsZero() {
+		input.EndTime = aws.Int64(r.query.End.UnixMilli())
+	}
+
+	if r.query.Filter != "" {
+		input.FilterPattern = &r.query.Filter
+	}
+
+	return input, nil
+}
+
+// fetch reads all the pages of events from the start time, calling handler for each event.
+// retries is the number of times a throttled request is retried, negative retries forever.
+func (r *Reader) fetch(ctx context.Context, start time.Time, retries int, handler func(*Event) error) error {
+	input, err := r.filterInput(start)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var page *cloudwatchlogs.FilterLogEventsOutput
+
+		err := retry(ctx, r.sleep, retries, func() error {
+			var err error
+			page, err = r.api.FilterLogEvents(ctx, input)
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Events {
+			e := page.Events[i]
+			event := Event{
+				ID:        aws.ToString(e.EventId),
+				Timestamp: time.UnixMilli(aws.ToInt64(e.Timestamp)),
+				Stream:    aws.ToString(e.LogStreamName),
+				Message:   aws.ToString(e.Message),
+				App:       r.query.App,
+			}
+
+			if err := handler(&event); err != nil {
+				return err
+			}
+		}
+
+		if page.NextToken == nil || aws.ToString(page.NextToken) == aws.ToString(input.NextToken) {
+			return nil
+		}
+
+		input.NextToken = page.NextToken
+	}
+}
+
+// Events calls handler for each event matching the query, oldest first
+func (r *Reader) Events(ctx context.Context, handler func(*Event) error) error {
+	return r.fetch(ctx, r.query.Start, maxThrottledRetries, handler)
+}
+
+// Follow calls handler for each event matching the query and keeps polling
+// for new events until the context is cancelled or handler returns an error.
+// Each event is only passed to handler once.
+func (r *Reader) Follow(ctx context.Context, handler func(*Event) error) error {
+	return r.follow(ctx, handler, nil)
+}
+
+// follow is Follow, calling polled (if set) after each poll with the time the poll started
+func (r *Reader) follow(ctx context.Context, handler func(*Event) error, polled func(time.Time) error) error {
+	start := r.query.Start
+	newest := start
+	// events seen in the lookback window, keyed by ID
+	seen := map[string]time.Time{}
+
+	for {
+		pollStart := time.Now()
+
+		err := r.fetch(ctx, start, -1, func(event *Event) error {
+			if _, ok := seen[event.ID]; ok {
+				return nil
+			}
+
+			seen[event.ID] = event.Timestamp
+			if event.Timestamp.After(newest) {
+				newest = event.Timestamp
+			}
+
+			return handler(event)
+		})
+		if err != nil {
+			return err
+		}
+
+		if polled != nil {
+			if err := polled(pollStart); err != nil {
+				return err
+			}
+		}
+
+		if lookback := newest.Add(-followLookback); lookback.After(start) {
+			start = lookback
+		}
+
+		for id, timestamp := range seen {
+			if timestamp.Before(start) {
+				delete(seen, id)
+			}
+		}
+
+		if err := r.sleep(ctx, PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// IsThrottlingError reports whether the error is from CloudWatch Logs rate limiting
+func IsThrottlingError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "LimitExceededException", "TooManyRequestsException":
+		return true
+	}
+
+	return false
+}
+
+// retry calls fn, retrying with exponential backoff while it is throttled.
+// retries is the maximum number of retries, negative retries forever.
+func retry(ctx context.Context, sleep sleepFunc, retries int, fn func() error) error {
+	backoff := time.Second
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !IsThrottlingError(err) || (retries >= 0 && attempt >= retries) {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{"backoff": backoff}).Debug("CloudWatch Logs request throttled")
+
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
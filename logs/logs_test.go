@@ -0,0 +1,195 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+)
+
+var errThrottled = &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+
+// fakeAPI returns the queued responses to FilterLogEvents in order
+type fakeAPI struct {
+	responses []*cloudwatchlogs.FilterLogEventsOutput
+	errs      []error
+	inputs    []cloudwatchlogs.FilterLogEventsInput
+}
+
+func (f *fakeAPI) DescribeLogStreams(_ context.Context, _ *cloudwatchlogs.DescribeLogStreamsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{}, nil
+}
+
+func (f *fakeAPI) FilterLogEvents(_ context.Context, input *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.inputs = append(f.inputs, *input)
+
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(f.responses) == 0 {
+		return &cloudwatchlogs.FilterLogEventsOutput{}, nil
+	}
+
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+
+	return resp, nil
+}
+
+func event(id string, ms int64) types.FilteredLogEvent {
+	return types.FilteredLogEvent{
+		EventId:       aws.String(id),
+		Timestamp:     aws.Int64(ms),
+		LogStreamName: aws.String("web/web/abc"),
+		Message:       aws.String("message " + id),
+	}
+}
+
+func newTestReader(api *fakeAPI, query Query) (*Reader, *[]time.Duration) {
+	var sleeps []time.Duration
+
+	r := NewReaderWithAPI(api, query)
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+
+		return nil
+	}
+
+	return r, &sleeps
+}
+
+func TestReaderEventsPagination(t *testing.T) {
+	t.Parallel()
+
+	api := &fakeAPI{
+		responses: []*cloudwatchlogs.FilterLogEventsOutput{
+			{Events: []types.FilteredLogEvent{event("a", 1000), event("b", 2000)}, NextToken: aws.String("page2")},
+			{Events: []types.FilteredLogEvent{event("c", 3000)}},
+		},
+		errs: []error{nil, errThrottled, errThrottled, nil},
+	}
+	start := time.UnixMilli(500)
+	r, sleeps := newTestReader(api, Query{Group: "group", Prefix: "web", Filter: "ERROR", Start: start})
+
+	var ids []string
+
+	err := r.Events(context.Background(), func(e *Event) error {
+		ids = append(ids, e.ID)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"a", "b", "c"}) {
+		t.Errorf("expected [a b c], got %v", ids)
+	}
+
+	if !reflect.DeepEqual(*sleeps, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("expected exponential backoff, got %v", *sleeps)
+	}
+
+	first := api.inputs[0]
+	if aws.ToString(first.LogStreamNamePrefix) != "web" || aws.ToString(first.FilterPattern) != "ERROR" || aws.ToInt64(first.StartTime) != 500 || first.EndTime != nil {
+		t.Errorf("unexpected input %+v", first)
+	}
+
+	if last := api.inputs[len(api.inputs)-1]; aws.ToString(last.NextToken) != "page2" {
+		t.Errorf("expected page2 token, got %s", aws.ToString(last.NextToken))
+	}
+}
+
+func TestReaderEventsErrors(t *testing.T) {
+	t.Parallel()
+
+	errOther := errors.New("access denied")
+	api := &fakeAPI{errs: []error{errOther}}
+	r, _ := newTestReader(api, Query{Group: "group"})
+
+	if err := r.Events(context.Background(), func(*Event) error { return nil }); !errors.Is(err, errOther) {
+		t.Errorf("expected %s, got %s", errOther, err)
+	}
+
+	throttled := make([]error, maxThrottledRetries+1)
+	for i := range throttled {
+		throttled[i] = errThrottled
+	}
+
+	r, _ = newTestReader(&fakeAPI{errs: throttled}, Query{Group: "group"})
+	if err := r.Events(context.Background(), func(*Event) error { return nil }); !IsThrottlingError(err) {
+		t.Errorf("expected throttling error after retries, got %v", err)
+	}
+}
+
+func TestReaderFollowDedupe(t *testing.T) {
+	t.Parallel()
+
+	api := &fakeAPI{
+		responses: []*cloudwatchlogs.FilterLogEventsOutput{
+			{Events: []types.FilteredLogEvent{event("a", 1000), event("b", 20000)}},
+			// b is returned again along with a late event and a new one
+			{Events: []types.FilteredLogEvent{event("late", 15000), event("b", 20000), event("c", 21000)}},
+			{Events: []types.FilteredLogEvent{event("c", 21000)}},
+		},
+	}
+	r, _ := newTestReader(api, Query{Group: "group", Start: time.UnixMilli(0)})
+	errDone := errors.New("done")
+	polls := 0
+	r.sleep = func(context.Context, time.Duration) error {
+		polls++
+		if polls == 3 {
+			return errDone
+		}
+
+		return nil
+	}
+
+	var ids []string
+
+	err := r.Follow(context.Background(), func(e *Event) error {
+		ids = append(ids, e.ID)
+
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected %s, got %s", errDone, err)
+	}
+
+	if len(api.inputs) < 2 || aws.ToInt64(api.inputs[1].StartTime) != 10000 {
+		t.Errorf("expected second poll to start at 10000, got %+v", api.inputs)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"a", "b", "late", "c"}) {
+		t.Errorf("expected [a b late c], got %v", ids)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	e := &Event{Timestamp: time.Now(), Stream: "web", Message: "{\"level\":\"info\"}\n"}
+
+	raw := Output{Raw: true}
+	if actual := raw.Format(e); actual != `{"level":"info"}` {
+		t.Errorf("expected message without trailing newline, got %q", actual)
+	}
+
+	pretty := Output{}
+	if actual := pretty.Format(&Event{Timestamp: time.Now(), Stream: "web", Message: "plain text"}); !strings.Contains(actual, "web") || !strings.HasSuffix(actual, " plain text") {
+		t.Errorf("expected stream and message, got %q", actual)
+	}
+}
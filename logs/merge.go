@@ -0,0 +1,234 @@
+package logs
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// mergeDelay is how far behind its last poll a reader without new events is assumed to be,
+// so a quiet reader doesn't hold back the events of the others
+const mergeDelay = 3 * time.Second
+
+// eventQueue orders events by timestamp, oldest first
+type eventQueue []*Event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool { return q[i].Timestamp.Before(q[j].Timestamp) }
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x any) { *q = append(*q, x.(*Event)) }
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return e
+}
+
+// flush calls handler for the queued events older than before, oldest first.
+// The zero time flushes all the events.
+func (q *eventQueue) flush(before time.Time, handler func(*Event) error) error {
+	for q.Len() > 0 && (before.IsZero() || (*q)[0].Timestamp.Before(before)) {
+		if err := handler(heap.Pop(q).(*Event)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EventsAll calls handler for each event matching the readers' queries, oldest first.
+// The readers are read concurrently.
+func EventsAll(ctx context.Context, readers []*Reader, handler func(*Event) error) error {
+	if len(readers) == 1 {
+		return readers[0].Events(ctx, handler)
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		events []*Event
+	)
+
+	errs := make([]error, len(readers))
+
+	for i, r := range readers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = r.Events(ctx, func(e *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				events = append(events, e)
+
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	slices.SortStableFunc(events, func(a, b *Event) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	for _, e := range events {
+		if err := handler(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watermarks are the times each reader has read up to. The zero time means the reader hasn't finished a poll.
+type watermarks []time.Time
+
+// min is the time all the readers have read up to, or the zero time if any reader hasn't finished a poll
+func (w watermarks) min() time.Time {
+	var oldest time.Time
+
+	for i, t := range w {
+		if t.IsZero() {
+			return time.Time{}
+		}
+
+		if i == 0 || t.Before(oldest) {
+			oldest = t
+		}
+	}
+
+	return oldest
+}
+
+// followUpdate is an event or the end of a poll from one of the readers in FollowAll
+type followUpdate struct {
+	reader int
+	event  *Event
+	// polled is the time the finished poll started
+	polled time.Time
+}
+
+// FollowAll prints the events so far from all the readers, like EventsAll, then follows the readers
+// concurrently, calling handler for each new event in timestamp order. New events are only passed to
+// handler once every reader has read past them: a reader has read up to its newest event or, if it is
+// quiet, to shortly before its last poll.
+// It returns when the context is cancelled or any reader or the handler returns an error.
+func FollowAll(ctx context.Context, readers []*Reader, handler func(*Event) error) error {
+	if len(readers) == 1 {
+		return readers[0].Follow(ctx, handler)
+	}
+
+	// read the backlog in one ordered pass, then follow from shortly before it ended
+	cutoff := time.Now()
+	backlog := make([]*Reader, 0, len(readers))
+	followers := make([]*Reader, 0, len(readers))
+
+	for _, r := range readers {
+		b, f := *r, *r
+		if b.query.End.IsZero() || b.query.End.After(cutoff) {
+			b.query.End = cutoff
+		}
+
+		if start := cutoff.Add(-followLookback); start.After(f.query.Start) {
+			f.query.Start = start
+		}
+
+		backlog = append(backlog, &b)
+		followers = append(followers, &f)
+	}
+
+	// events in the backlog which the followers will read again
+	printed := map[string]bool{}
+
+	err := EventsAll(ctx, backlog, func(e *Event) error {
+		if !e.Timestamp.Before(cutoff.Add(-followLookback)) {
+			printed[e.ID] = true
+		}
+
+		return handler(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan followUpdate)
+	errs := make(chan error, len(followers))
+
+	send := func(u followUpdate) error {
+		select {
+		case updates <- u:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for i, r := range followers {
+		go func() {
+			errs <- r.follow(ctx, func(e *Event) error {
+				if printed[e.ID] {
+					return nil
+				}
+
+				return send(followUpdate{reader: i, event: e})
+			}, func(polled time.Time) error {
+				return send(followUpdate{reader: i, polled: polled})
+			})
+		}()
+	}
+
+	queue := eventQueue{}
+	marks := make(watermarks, len(followers))
+	newest := make([]time.Time, len(followers))
+
+	for {
+		select {
+		case u := <-updates:
+			if u.event != nil {
+				heap.Push(&queue, u.event)
+
+				if u.event.Timestamp.After(newest[u.reader]) {
+					newest[u.reader] = u.event.Timestamp
+				}
+
+				continue
+			}
+
+			marks[u.reader] = u.polled.Add(-mergeDelay)
+			if newest[u.reader].After(marks[u.reader]) {
+				marks[u.reader] = newest[u.reader]
+			}
+
+			if watermark := marks.min(); !watermark.IsZero() {
+				if err := queue.flush(watermark, handler); err != nil {
+					return err
+				}
+			}
+		case err := <-errs:
+			// print what was read before the reader stopped
+			if flushErr := queue.flush(time.Time{}, handler); flushErr != nil {
+				return flushErr
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package logs
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestEventQueueFlush(t *testing.T) {
+	t.Parallel()
+
+	queue := eventQueue{}
+	for _, ms := range []int64{3000, 1000, 2000} {
+		heap.Push(&queue, &Event{ID: string(rune('a' + ms/1000 - 1)), Timestamp: time.UnixMilli(ms)})
+	}
+
+	var ids []string
+
+	collect := func(e *Event) error {
+		ids = append(ids, e.ID)
+
+		return nil
+	}
+
+	if err := queue.flush(time.UnixMilli(2500), collect); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"a", "b"}) || queue.Len() != 1 {
+		t.Errorf("expected a and b to be flushed, got %v", ids)
+	}
+
+	if err := queue.flush(time.Time{}, collect); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"a", "b", "c"}) || queue.Len() != 0 {
+		t.Errorf("expected all events to be flushed, got %v", ids)
+	}
+}
+
+func appReader(app string, timestamps ...int64) *Reader {
+	events := make([]types.FilteredLogEvent, 0, len(timestamps))
+	for _, ms := range timestamps {
+		events = append(events, event(app+string(rune('0'+ms/1000)), ms))
+	}
+
+	api := &fakeAPI{responses: []*cloudwatchlogs.FilterLogEventsOutput{{Events: events}}}
+
+	return NewReaderWithAPI(api, Query{Group: app, Start: time.UnixMilli(0), App: app})
+}
+
+func TestEventsAll(t *testing.T) {
+	t.Parallel()
+
+	readers := []*Reader{appReader("api", 1000, 3000), appReader("worker", 2000, 4000)}
+
+	var ids []string
+
+	err := EventsAll(context.Background(), readers, func(e *Event) error {
+		ids = append(ids, e.ID)
+
+		if !strings.HasPrefix(e.ID, e.App) {
+			t.Errorf("expected event %s to be labeled with its app, got %s", e.ID, e.App)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"api1", "worker2", "api3", "worker4"}) {
+		t.Errorf("expected events interleaved by timestamp, got %v", ids)
+	}
+}
+
+func TestFollowAll(t *testing.T) {
+	t.Parallel()
+
+	readers := []*Reader{appReader("api", 1000, 3000), appReader("worker", 2000, 4000)}
+	errDone := errors.New("done")
+
+	var ids []string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := FollowAll(ctx, readers, func(e *Event) error {
+		ids = append(ids, e.ID)
+		if len(ids) == 4 {
+			return errDone
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected %s, got %v", errDone, err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"api1", "worker2", "api3", "worker4"}) {
+		t.Errorf("expected events interleaved by timestamp, got %v", ids)
+	}
+}
+
+func TestFollowAllAfterBacklog(t *testing.T) {
+	t.Parallel()
+
+	api := appReader("api", 1000, 3000)
+	api.api.(*fakeAPI).responses = append(api.api.(*fakeAPI).responses, &cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{event("api5", 5000)},
+	})
+	readers := []*Reader{api, appReader("worker", 2000, 4000)}
+	errDone := errors.New("done")
+
+	var ids []string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := FollowAll(ctx, readers, func(e *Event) error {
+		ids = append(ids, e.ID)
+		if len(ids) == 5 {
+			return errDone
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected %s, got %v", errDone, err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"api1", "worker2", "api3", "worker4", "api5"}) {
+		t.Errorf("expected the backlog in timestamp order then new events, got %v", ids)
+	}
+}
+
+func TestWatermarksMin(t *testing.T) {
+	t.Parallel()
+
+	if m := (watermarks{time.UnixMilli(2000), time.Time{}}).min(); !m.IsZero() {
+		t.Errorf("expected the zero time until every reader has polled, got %s", m)
+	}
+
+	if m := (watermarks{time.UnixMilli(2000), time.UnixMilli(1000), time.UnixMilli(3000)}).min(); !m.Equal(time.UnixMilli(1000)) {
+		t.Errorf("expected the oldest watermark, got %s", m)
+	}
+}
+
+func TestFormatApp(t *testing.T) {
+	t.Parallel()
+
+	output := Output{Apps: []string{"api", "worker"}}
+	line := output.Format(&Event{Timestamp: time.Unix(0, 0), Stream: "web/web/abc", Message: "hello", App: "worker"})
+
+	if !strings.Contains(line, "worker") || !strings.HasSuffix(line, "hello") {
+		t.Errorf("expected app label in %q", line)
+	}
+
+	fields, _ := EventFields(&Event{Message: "{}", App: "api"})
+	if fields[FieldApp] != "api" {
+		t.Errorf("expected @app field, got %v", fields[FieldApp])
+	}
+}
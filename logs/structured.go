@@ -0,0 +1,257 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields available on every event, in addition to the fields of JSON messages
+const (
+	FieldTimestamp = "@timestamp"
+	FieldStream    = "@stream"
+	FieldMessage   = "@message"
+	// FieldApp is only set when reading the logs of several apps
+	FieldApp = "@app"
+)
+
+// conditionOperators are checked in order so two character operators match before their prefixes
+var conditionOperators = []string{">=", "<=", "!=", "~", "=", ">", "<"}
+
+// Condition compares a field of a structured log message to a value, e.g. status>=500
+type Condition struct {
+	Field    string
+	Operator string
+	Value    string
+	re       *regexp.Regexp
+}
+
+// ParseCondition parses a <field><operator><value> expression.
+// Operators are =, !=, >, >=, <, <=, and ~ (regular expression match).
+func ParseCondition(expression string) (*Condition, error) {
+	index := -1
+
+	var operator string
+
+	for _, op := range conditionOperators {
+		if i := strings.Index(expression, op); i > 0 && (index < 0 || i < index || (i == index && len(op) > len(operator))) {
+			index = i
+			operator = op
+		}
+	}
+
+	if index < 0 {
+		return nil, fmt.Errorf("invalid condition %q -- expected <field><operator><value> with one of %s", expression, strings.Join(conditionOperators, " "))
+	}
+
+	c := Condition{
+		Field:    strings.TrimSpace(expression[:index]),
+		Operator: operator,
+		Value:    strings.TrimSpace(expression[index+len(operator):]),
+	}
+
+	if operator == "~" {
+		re, err := regexp.Compile(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: %w", expression, err)
+		}
+
+		c.re = re
+	}
+
+	return &c, nil
+}
+
+// Match reports whether the fields satisfy the condition.
+// Values are compared as numbers when both sides are numeric, otherwise as strings.
+// A missing field only satisfies the != operator.
+func (c *Condition) Match(fields map[string]any) bool {
+	value, ok := lookupField(fields, c.Field)
+	if !ok {
+		return c.Operator == "!="
+	}
+
+	actual := fieldString(value)
+
+	if c.Operator == "~" {
+		return c.re.MatchString(actual)
+	}
+
+	var cmp int
+
+	actualNumber, actualErr := strconv.ParseFloat(actual, 64)
+	expectedNumber, expectedErr := strconv.ParseFloat(c.Value, 64)
+
+	if actualErr == nil && expectedErr == nil {
+		switch {
+		case actualNumber < expectedNumber:
+			cmp = -1
+		case actualNumber > expectedNumber:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(actual, c.Value)
+	}
+
+	switch c.Operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// EventFields returns the fields of the event's JSON message along with the
+// @timestamp, @stream, and @message fields. ok is false if the message is not a JSON object.
+func EventFields(event *Event) (fields map[string]any, ok bool) {
+	fields = map[string]any{}
+
+	decoder := json.NewDecoder(strings.NewReader(event.Message))
+	// keep numbers as written rather than converting them to floats
+	decoder.UseNumber()
+
+	ok = decoder.Decode(&fields) == nil
+	if !ok {
+		fields = map[string]any{}
+	}
+
+	fields[FieldTimestamp] = event.Timestamp.Format(time.RFC3339Nano)
+	fields[FieldStream] = event.Stream
+	fields[FieldMessage] = strings.TrimRight(event.Message, "\n")
+
+	if event.App != "" {
+		fields[FieldApp] = event.App
+	}
+
+	return fields, ok
+}
+
+// lookupField finds the value of a field, using dots to reference nested objects, e.g. http.status
+func lookupField(fields map[string]any, name string) (any, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+
+	head, rest, found := strings.Cut(name, ".")
+	if !found {
+		return nil, false
+	}
+
+	nested, ok := fields[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	return lookupField(nested, rest)
+}
+
+// fieldString returns the value as it would appear in the log message, without quotes for strings
+func fieldString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case json.Number:
+		return v.String()
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(out)
+	}
+}
+
+// structured reports whether the output filters or projects JSON messages
+func (o *Output) structured() bool {
+	return len(o.Where) > 0 || len(o.Fields) > 0 || o.JSONLines
+}
+
+// Render formats the event for output, applying the Where conditions and Fields projection.
+// ok is false if the event should be skipped.
+func (o *Output) Render(event *Event) (line string, ok bool) {
+	if !o.structured() {
+		return o.Format(event), true
+	}
+
+	fields, isJSON := EventFields(event)
+	if !isJSON && (len(o.Where) > 0 || len(o.Fields) > 0) {
+		return "", false
+	}
+
+	for _, c := range o.Where {
+		if !c.Match(fields) {
+			return "", false
+		}
+	}
+
+	if o.JSONLines {
+		return jsonLine(fields, o.Fields), true
+	}
+
+	if len(o.Fields) == 0 {
+		return o.Format(event), true
+	}
+
+	values := make([]string, 0, len(o.Fields))
+	for _, name := range o.Fields {
+		value, _ := lookupField(fields, name)
+		values = append(values, fieldString(value))
+	}
+
+	if o.Raw {
+		return strings.Join(values, "\t"), true
+	}
+
+	return fmt.Sprintf("%s %s", o.prefix(event), strings.Join(values, "\t")), true
+}
+
+// jsonLine encodes the selected fields (or all the fields) as a single line of JSON.
+// Selected fields are written in the order they were given.
+func jsonLine(fields map[string]any, selected []string) string {
+	if len(selected) == 0 {
+		out, _ := json.Marshal(fields)
+
+		return string(out)
+	}
+
+	buf := bytes.NewBufferString("{")
+
+	for i, name := range selected {
+		value, _ := lookupField(fields, name)
+		key, _ := json.Marshal(name)
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			encoded = []byte("null")
+		}
+
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(encoded)
+	}
+
+	buf.WriteString("}")
+
+	return buf.String()
+}
@@ -0,0 +1,112 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCondition(t *testing.T) {
+	t.Parallel()
+
+	scenarios := map[string]Condition{
+		"level=error":       {Field: "level", Operator: "=", Value: "error"},
+		"status>=500":       {Field: "status", Operator: ">=", Value: "500"},
+		"status < 300":      {Field: "status", Operator: "<", Value: "300"},
+		"level!=debug":      {Field: "level", Operator: "!=", Value: "debug"},
+		"path=/a?b>=c":      {Field: "path", Operator: "=", Value: "/a?b>=c"},
+		"http.status>499":   {Field: "http.status", Operator: ">", Value: "499"},
+		"msg~timeout|reset": {Field: "msg", Operator: "~", Value: "timeout|reset"},
+	}
+
+	for expression, expected := range scenarios {
+		c, err := ParseCondition(expression)
+		if err != nil {
+			t.Errorf("%s: %s", expression, err)
+
+			continue
+		}
+
+		if c.Field != expected.Field || c.Operator != expected.Operator || c.Value != expected.Value {
+			t.Errorf("%s: expected %+v, got %+v", expression, expected, c)
+		}
+	}
+
+	for _, expression := range []string{"level", "=error", "msg~("} {
+		if _, err := ParseCondition(expression); err == nil {
+			t.Errorf("expected error for %q, got nil", expression)
+		}
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	t.Parallel()
+
+	fields, ok := EventFields(&Event{Message: `{"level": "error", "status": 502, "http": {"path": "/api"}, "ok": false}`})
+	if !ok {
+		t.Fatal("expected JSON message")
+	}
+
+	scenarios := map[string]bool{
+		"level=error":     true,
+		"level=info":      false,
+		"status>=500":     true,
+		"status>=1000":    false,
+		"status=502.0":    true,
+		"status<99":       false,
+		"http.path=/api":  true,
+		"http.path~^/a":   true,
+		"ok=false":        true,
+		"missing=1":       false,
+		"missing!=1":      true,
+		"@message~status": true,
+	}
+
+	for expression, expected := range scenarios {
+		c, err := ParseCondition(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := c.Match(fields); actual != expected {
+			t.Errorf("%s: expected %t, got %t", expression, expected, actual)
+		}
+	}
+}
+
+func TestOutputRender(t *testing.T) {
+	t.Parallel()
+
+	where, err := ParseCondition("status>=500")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	serverError := &Event{Timestamp: timestamp, Stream: "web", Message: `{"ts": "12:00", "level": "error", "msg": "boom", "status": 502}`}
+	success := &Event{Timestamp: timestamp, Stream: "web", Message: `{"ts": "12:01", "level": "info", "msg": "ok", "status": 200}`}
+	text := &Event{Timestamp: timestamp, Stream: "web", Message: "Traceback (most recent call last):"}
+
+	output := Output{Raw: true, Where: []*Condition{where}, Fields: []string{"ts", "level", "msg", "missing"}}
+
+	if line, ok := output.Render(serverError); !ok || line != "12:00\terror\tboom\t" {
+		t.Errorf("expected projected fields, got %q", line)
+	}
+
+	for _, e := range []*Event{success, text} {
+		if line, ok := output.Render(e); ok {
+			t.Errorf("expected %q to be skipped, got %q", e.Message, line)
+		}
+	}
+
+	output.JSONLines = true
+	if line, _ := output.Render(serverError); line != `{"ts":"12:00","level":"error","msg":"boom","missing":null}` {
+		t.Errorf("expected JSON line with selected fields in order, got %q", line)
+	}
+
+	all := Output{JSONLines: true}
+	expected := `{"@message":"Traceback (most recent call last):","@stream":"web","@timestamp":"2024-03-01T12:00:00Z"}`
+
+	if line, ok := all.Render(text); !ok || line != expected {
+		t.Errorf("expected %s, got %s", expected, line)
+	}
+}
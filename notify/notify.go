@@ -0,0 +1,242 @@
+// Package notify tells the user when a watched build or deploy finishes,
+// with a desktop notification, a terminal bell or a webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MethodDesktop shows a desktop notification
+	MethodDesktop = "desktop"
+	// MethodBell rings the terminal bell
+	MethodBell = "bell"
+	// MethodNone disables notifications from the config file
+	MethodNone = "none"
+
+	configFile     = "notifications.yml"
+	webhookTimeout = 10 * time.Second
+)
+
+// Config selects how the user is notified
+type Config struct {
+	Desktop bool   `yaml:"desktop"`
+	Bell    bool   `yaml:"bell"`
+	Webhook string `yaml:"webhook"`
+}
+
+// Event is the outcome of a watched build or deploy
+type Event struct {
+	// Event is what finished, e.g. "build" or "rollback"
+	Event       string  `json:"event"`
+	App         string  `json:"app"`
+	BuildNumber int     `json:"build_number"`
+	Commit      string  `json:"commit"`
+	Result      string  `json:"result"`
+	Duration    float64 `json:"duration_seconds"`
+	Message     string  `json:"message,omitempty"`
+	// Text summarizes the event for chat webhooks like Slack and Teams
+	Text string `json:"text"`
+}
+
+// NewEvent creates an event with the summary text filled in
+func NewEvent(event, app string, buildNumber int, commit, result string, duration time.Duration, message string) *Event {
+	e := &Event{
+		Event:       event,
+		App:         app,
+		BuildNumber: buildNumber,
+		Commit:      commit,
+		Result:      result,
+		Duration:    duration.Round(time.Second).Seconds(),
+		Message:     message,
+	}
+	e.Text = e.Title() + ": " + e.Summary()
+
+	return e
+}
+
+// Title is a short description of what finished
+func (e *Event) Title() string {
+	return fmt.Sprintf("%s %s #%d", e.App, e.Event, e.BuildNumber)
+}
+
+// Summary describes the result
+func (e *Event) Summary() string {
+	summary := fmt.Sprintf("%s after %s", strings.ReplaceAll(e.Result, "_", " "), time.Duration(e.Duration)*time.Second)
+
+	if len(e.Commit) > 7 {
+		summary += fmt.Sprintf(" (%s)", e.Commit[:7])
+	} else if e.Commit != "" {
+		summary += fmt.Sprintf(" (%s)", e.Commit)
+	}
+
+	return summary
+}
+
+// ConfigPath is the location of the user's notification config
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "apppack", configFile), nil
+}
+
+// LoadConfig reads the notification config from the file. A missing file disables notifications.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseMethods creates a config from a list of methods (desktop, bell or none) and a webhook URL
+func ParseMethods(methods []string, webhook string) (*Config, error) {
+	config := &Config{Webhook: webhook}
+
+	for _, method := range methods {
+		switch strings.TrimSpace(method) {
+		case MethodDesktop:
+			config.Desktop = true
+		case MethodBell:
+			config.Bell = true
+		case MethodNone:
+		default:
+			return nil, fmt.Errorf("unknown notification %q -- must be %s, %s or %s", method, MethodDesktop, MethodBell, MethodNone)
+		}
+	}
+
+	return config, nil
+}
+
+// Enabled reports whether any notification method is configured
+func (c *Config) Enabled() bool {
+	return c.Desktop || c.Bell || c.Webhook != ""
+}
+
+// Notifier sends notifications with the configured methods
+type Notifier struct {
+	Config *Config
+	// Bell is where the terminal bell is written
+	Bell   io.Writer
+	client *http.Client
+	run    func(name string, args ...string) error
+}
+
+// NewNotifier creates a notifier which rings the bell on stderr
+func NewNotifier(config *Config) *Notifier {
+	return &Notifier{
+		Config: config,
+		Bell:   os.Stderr,
+		client: &http.Client{Timeout: webhookTimeout},
+		run: func(name string, args ...string) error {
+			return exec.Command(name, args...).Run()
+		},
+	}
+}
+
+// Notify sends the event with every configured method. A failing method doesn't stop the others.
+func (n *Notifier) Notify(ctx context.Context, event *Event) error {
+	var errs []error
+
+	if n.Config.Bell {
+		if _, err := io.WriteString(n.Bell, "\a"); err != nil {
+			errs = append(errs, fmt.Errorf("bell: %w", err))
+		}
+	}
+
+	if n.Config.Desktop {
+		if err := n.desktop(event); err != nil {
+			errs = append(errs, fmt.Errorf("desktop notification: %w", err))
+		}
+	}
+
+	if n.Config.Webhook != "" {
+		if err := n.webhook(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) desktop(event *Event) error {
+	name, args, err := desktopCommand(runtime.GOOS, event.Title(), event.Summary())
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"command": name}).Debug("showing desktop notification")
+
+	return n.run(name, args...)
+}
+
+// desktopCommand returns the command which shows a desktop notification on the OS
+func desktopCommand(goos, title, message string) (string, []string, error) {
+	switch goos {
+	case "darwin":
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+		return "osascript", []string{"-e", fmt.Sprintf(`display notification "%s" with title "%s"`, quote.Replace(message), quote.Replace(title))}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "notify-send", []string{"--app-name=AppPack", title, message}, nil
+	default:
+		return "", nil, fmt.Errorf("not supported on %s", goos)
+	}
+}
+
+func (n *Notifier) webhook(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Config.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	logrus.WithFields(logrus.Fields{"url": n.Config.Webhook}).Debug("posting notification webhook")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	return nil
+}
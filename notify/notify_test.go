@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	t.Parallel()
+
+	event := NewEvent("build", "my-app", 42, "0a1b2c3d4e5f", "test_failed", 95*time.Second+400*time.Millisecond, "Test failed")
+
+	if event.Duration != 95 {
+		t.Errorf("expected duration rounded to 95 seconds, got %v", event.Duration)
+	}
+
+	expected := "my-app build #42: test failed after 1m35s (0a1b2c3)"
+	if event.Text != expected {
+		t.Errorf("expected %q, got %q", expected, event.Text)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	config, err := LoadConfig(filepath.Join(dir, "missing.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Enabled() {
+		t.Errorf("expected notifications to be disabled without a config file, got %+v", config)
+	}
+
+	path := filepath.Join(dir, configFile)
+	if err = os.WriteFile(path, []byte("bell: true\nwebhook: https://example.com/hook\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *config != (Config{Bell: true, Webhook: "https://example.com/hook"}) {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	if err = os.WriteFile(path, []byte("bell: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = LoadConfig(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected error naming %s, got %v", path, err)
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseMethods([]string{"desktop", "bell"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !config.Desktop || !config.Bell || config.Webhook != "" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	config, err = ParseMethods([]string{"none"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Enabled() {
+		t.Errorf("expected none to disable notifications, got %+v", config)
+	}
+
+	if _, err = ParseMethods([]string{"email"}, ""); err == nil {
+		t.Error("expected error for an unknown method, got nil")
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	t.Parallel()
+
+	name, args, err := desktopCommand("darwin", `my-app build #1`, `failed "quoted"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "osascript" || args[1] != `display notification "failed \"quoted\"" with title "my-app build #1"` {
+		t.Errorf("unexpected command %s %v", name, args)
+	}
+
+	if name, _, _ := desktopCommand("linux", "title", "message"); name != "notify-send" {
+		t.Errorf("expected notify-send, got %s", name)
+	}
+
+	if _, _, err := desktopCommand("plan9", "title", "message"); err == nil {
+		t.Error("expected error for an unsupported OS, got nil")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+
+	var received Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected JSON content type, got %s", r.Header.Get("Content-Type"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	bell := &bytes.Buffer{}
+	var ran []string
+
+	n := NewNotifier(&Config{Bell: true, Desktop: true, Webhook: server.URL})
+	n.Bell = bell
+	n.run = func(name string, _ ...string) error {
+		ran = append(ran, name)
+
+		return errors.New("not installed")
+	}
+
+	event := NewEvent("build", "my-app", 42, "abc", "success", time.Minute, "")
+
+	err := n.Notify(context.Background(), event)
+	// the desktop notification fails (or isn't supported on this OS), the others are still sent
+	if err == nil || !strings.Contains(err.Error(), "desktop notification") {
+		t.Errorf("expected desktop notification error, got %v", err)
+	}
+
+	if bell.String() != "\a" {
+		t.Errorf("expected the bell to ring, got %q", bell.String())
+	}
+
+	if received != *event {
+		t.Errorf("expected webhook payload %+v, got %+v", *event, received)
+	}
+}
+
+func TestNotifyWebhookError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(&Config{Webhook: server.URL})
+
+	err := n.Notify(context.Background(), NewEvent("build", "my-app", 42, "abc", "success", time.Minute, ""))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected 403 error, got %v", err)
+	}
+}